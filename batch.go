@@ -0,0 +1,87 @@
+package task_manager
+
+import (
+	"time"
+)
+
+type BatchMode string
+
+const (
+	// BatchAtomic applies all operations or, if any of them fails, none.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies every operation that succeeds.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+type BatchOp string
+
+const (
+	BatchCreate   BatchOp = "create"
+	BatchUpdate   BatchOp = "update"
+	BatchComplete BatchOp = "complete"
+	BatchDelete   BatchOp = "delete"
+)
+
+type BatchStatus string
+
+const (
+	BatchOk         BatchStatus = "ok"
+	BatchFailed     BatchStatus = "error"
+	BatchRolledBack BatchStatus = "rolled_back"
+	BatchSkipped    BatchStatus = "skipped"
+)
+
+// BatchTask holds the fields of a created task or the changes of updated
+// ones. ListId and Tags apply to created tasks only, ClearDueAt to updated
+// ones.
+type BatchTask struct {
+	Text       *string    `json:"text"`
+	StartTime  *time.Time `json:"start_time"`
+	Priority   *Priority  `json:"priority"`
+	DueAt      *time.Time `json:"due_at"`
+	ClearDueAt bool       `json:"clear_due_at"`
+	ListId     *int       `json:"list_id"`
+	Tags       []string   `json:"tags"`
+}
+
+// BatchFilter selects unarchived tasks of the batch's user.
+type BatchFilter struct {
+	Status    StatusEnd  `json:"status" enums:"START,END"`
+	ListId    int        `json:"list_id"`
+	Tags      []string   `json:"tags"`
+	StartFrom *time.Time `json:"start_from"`
+	StartTo   *time.Time `json:"start_to"`
+}
+
+// BatchOperation creates a task or changes the task Id, or all tasks
+// matching Filter.
+type BatchOperation struct {
+	Op BatchOp `json:"op" binding:"required" enums:"create,update,complete,delete"`
+	Id int     `json:"id"`
+	// Version of the task Id; zero skips the check.
+	Version int          `json:"version"`
+	Filter  *BatchFilter `json:"filter"`
+	Task    *BatchTask   `json:"task"`
+	// Shift moves start_time and due_at of updated tasks by seconds.
+	Shift int `json:"shift"`
+}
+
+type BatchInput struct {
+	TelegramId string           `json:"telegram_id" binding:"required"`
+	Mode       BatchMode        `json:"mode" enums:"atomic,best_effort"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+type BatchItemResult struct {
+	Index   int         `json:"index"`
+	Op      BatchOp     `json:"op"`
+	Status  BatchStatus `json:"status"`
+	TaskIds []int       `json:"task_ids"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	Mode      BatchMode         `json:"mode"`
+	Committed bool              `json:"committed"`
+	Results   []BatchItemResult `json:"results"`
+}
@@ -0,0 +1,62 @@
+package task_manager
+
+import (
+	"time"
+)
+
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	ImportSkipped ImportStatus = "skipped"
+	ImportFailed  ImportStatus = "failed"
+)
+
+// CalendarImportItem reports the outcome of one VEVENT or VTODO of an
+// imported calendar.
+type CalendarImportItem struct {
+	Uid     string       `json:"uid"`
+	Summary string       `json:"summary"`
+	Status  ImportStatus `json:"status"`
+	TaskId  int          `json:"task_id,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+}
+
+type CalendarImportReport struct {
+	Created int                  `json:"created"`
+	Skipped int                  `json:"skipped"`
+	Failed  int                  `json:"failed"`
+	Items   []CalendarImportItem `json:"items"`
+}
+
+func (r *CalendarImportReport) Add(item CalendarImportItem) {
+	switch item.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// TaskSeries groups the tasks created from a template at one time of day,
+// so calendar feeds can export them as one recurring component.
+type TaskSeries struct {
+	Id         int `db:"id"`
+	TelegramId int `db:"telegram_id"`
+	// Recurrence is the RRULE the start times of the tasks follow from
+	// StartTimeAt.
+	Recurrence  string    `db:"recurrence"`
+	StartTimeAt time.Time `db:"start_time_at"`
+	// Occurrences are the start times Recurrence gives, StartTimeAt first.
+	Occurrences []time.Time `db:"-"`
+}
+
+// CalendarFeed holds the tasks of a calendar feed and the series of those
+// created from templates.
+type CalendarFeed struct {
+	Tasks  []Task
+	Series []TaskSeries
+}
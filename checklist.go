@@ -0,0 +1,35 @@
+package task_manager
+
+import (
+	"time"
+)
+
+type ChecklistItem struct {
+	Id        int        `json:"id" db:"id"`
+	TaskId    int        `json:"task_id" db:"task_id"`
+	Text      string     `json:"text" db:"text"`
+	Done      bool       `json:"done" db:"done"`
+	Position  int        `json:"position" db:"position"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	DoneAt    *time.Time `json:"done_at" db:"done_at"`
+}
+
+type ChecklistItemInput struct {
+	Text string `form:"text" json:"text" binding:"required"`
+	// Position defaults to after the last item.
+	Position *int `form:"position" json:"position"`
+}
+
+type UpdateChecklistItemInput struct {
+	Text *string `form:"text" json:"text"`
+	Done *bool   `form:"done" json:"done"`
+}
+
+func (i UpdateChecklistItemInput) Empty() bool {
+	return i.Text == nil && i.Done == nil
+}
+
+// ChecklistOrderInput lists item ids of a checklist in the wanted order.
+type ChecklistOrderInput struct {
+	ItemIds []int `form:"item_ids" json:"item_ids" binding:"required"`
+}
@@ -2,21 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"go.uber.org/zap"
-	"log"
 	"log/slog"
-	"net/http"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"task_manager"
 	_ "task_manager/docs"
 	"task_manager/pkg/handler"
+	"task_manager/pkg/logging"
+	"task_manager/pkg/metrics"
+	"task_manager/pkg/ratelimit"
 	"task_manager/pkg/repository"
+	"task_manager/pkg/rpc"
 	"task_manager/pkg/service"
+	"task_manager/pkg/tracing"
+)
+
+const (
+	defaultPrincipalRateLimit = "1200/m:200"
+	defaultTelegramRateLimit  = "60/m:20"
+	defaultRouteRateLimits    = `{"POST /api/tasks/": {"principal": "600/m:100", "telegram": "10/m:5"}}`
+
+	defaultQuotaTier  = "free"
+	defaultQuotaTiers = `{
+		"free": {"max_active_tasks": 100, "max_tasks_per_day": 50, "max_text_length": 1000},
+		"premium": {"max_active_tasks": 1000, "max_tasks_per_day": 500, "max_text_length": 4000}
+	}`
 )
 
 // @title Task Manager API
@@ -27,17 +44,32 @@ import (
 // @BasePath /
 
 func main() {
-	//logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	//slog.SetDefault(logger)
-	zapConfig := zap.NewProductionConfig()
-	zapConfig.DisableCaller = true
-	zapConfig.Level.SetLevel(zap.DebugLevel)
-	logger, _ := zapConfig.Build()
+	envErr := godotenv.Load()
 
-	http.Handle("/", handlerLog{logger})
+	logger, err := logging.New(os.Stdout, logging.Config{
+		Level:  os.Getenv("LOG_LEVEL"),
+		Format: os.Getenv("LOG_FORMAT"),
+	})
+	if err != nil {
+		slog.Error("failed to initialize logger", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, _ int) {
+		logger.Debug("route registered", "method", httpMethod, "path", absolutePath, "handler", handlerName)
+	}
 
-	if err := godotenv.Load(); err != nil {
-		slog.Error(fmt.Sprintf("error loading env variables: %s", err.Error()))
+	if envErr != nil {
+		logger.Warn("error loading env variables", "error", envErr)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: "task_manager",
+		Exporter:    os.Getenv("TRACING_EXPORTER"),
+	})
+	if err != nil {
+		logger.Error("failed to initialize tracing", "error", err)
+		os.Exit(1)
 	}
 
 	db, err := repository.NewPostgresDB(repository.Config{
@@ -46,50 +78,163 @@ func main() {
 		Username: os.Getenv("POSTGRES_USER"),
 		Password: os.Getenv("POSTGRES_PASSWORD"),
 		DBName:   os.Getenv("POSTGRES_DB"),
-	})
+	}, logger)
 	if err != nil {
-		log.Fatalf("failed to initialize db: %s", err.Error())
+		logger.Error("failed to initialize db", "error", err)
+		os.Exit(1)
 	}
 
-	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
-	handlers := handler.NewHandler(services)
+	appMetrics := metrics.NewMetrics()
+	appMetrics.RegisterDB(db.DB, os.Getenv("POSTGRES_DB"))
 
-	server := new(task_manager.Server)
-	go func() {
-		if err := server.Run(os.Getenv("PORT"), handlers.InitRoutes()); err != nil {
-			log.Panicf("error occured while running http server: %s", err.Error())
-		}
-	}()
-
-	log.Println("task manager started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	repos := repository.NewRepository(db, appMetrics, logger)
+	appMetrics.RegisterTasks(repos.TaskManagerTask)
+	quotas, err := quotasFromEnv()
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	idempotencyKeyTTL, err := envDuration("IDEMPOTENCY_KEY_TTL", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	webhookMaxAttempts, err := envInt("WEBHOOK_MAX_ATTEMPTS", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	webhookAllowPrivate, err := envBool("WEBHOOK_ALLOW_PRIVATE", false)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	streamInterval, err := envDuration("STREAM_INTERVAL", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	services := service.NewService(repos, logger, service.Config{
+		Quotas:              quotas,
+		IdempotencyKeyTTL:   idempotencyKeyTTL,
+		WebhookMaxAttempts:  webhookMaxAttempts,
+		WebhookAllowPrivate: webhookAllowPrivate,
+		StreamInterval:      streamInterval,
+	})
+	rateLimits, err := rateLimitsFromEnv()
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	rateLimitStore := ratelimit.NewMemoryStore()
+	rateLimits.Store = rateLimitStore
+	socketOrigins := envList("SOCKET_ALLOWED_ORIGINS")
+	handlers := handler.NewHandler(services, appMetrics, logger, rateLimits, socketOrigins)
 
-	log.Println("task manager shutting down")
+	schedulerInterval, err := envDuration("SCHEDULER_INTERVAL", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	scheduler := service.NewScheduler(repos, schedulerInterval, logger)
 
-	if err := server.Shutdown(context.Background()); err != nil {
-		slog.Error(fmt.Sprintf("error occured on server shutting down: %s", err.Error()))
+	outboxInterval, err := envDuration("OUTBOX_INTERVAL", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	outboxMaxAttempts, err := envInt("OUTBOX_MAX_ATTEMPTS", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	outboxRetention, err := envDuration("OUTBOX_RETENTION", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+	dispatcher := service.NewDispatcher(repos.Outbox, service.DispatcherConfig{
+		Interval:    outboxInterval,
+		MaxAttempts: outboxMaxAttempts,
+		Retention:   outboxRetention,
+	}, logger)
+	dispatcher.AddSink(service.NewLogSink(logger))
+	dispatcher.AddSink(service.NewReminderSink(service.NewLogNotifier(logger), appMetrics.RemindersFired))
+	dispatcher.AddSink(services.Webhook)
+
+	shutdownTimeout, err := envDuration("SHUTDOWN_TIMEOUT", 0)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
 	}
 
-	if err := db.Close(); err != nil {
-		slog.Error(fmt.Sprintf("error occured on db connection close: %s", err.Error()))
+	grpcReflection, err := envBool("GRPC_REFLECTION", true)
+	if err != nil {
+		logger.Error("failed to read config", "error", err)
+		os.Exit(1)
 	}
+	grpcServer := rpc.NewServer(rpc.Config{
+		Addr:       os.Getenv("GRPC_ADDR"),
+		Reflection: grpcReflection,
+	}, services, logger)
+
+	server := task_manager.NewServer(task_manager.ServerConfig{
+		Port:            os.Getenv("PORT"),
+		ShutdownTimeout: shutdownTimeout,
+	}, handlers.InitRoutes(), logger)
+	server.AddWorker("rate limit cleanup", rateLimitStore.Run)
+	server.AddWorker("idempotency key purger", services.Idempotency.RunPurger)
+	server.AddWorker("reminder scheduler", scheduler.Run)
+	server.AddWorker("outbox dispatcher", dispatcher.Run)
+	server.AddWorker("webhook delivery", services.Webhook.RunDelivery)
+	server.AddWorker("event stream", services.Stream.Run)
+	server.AddWorker("grpc server", grpcServer.Run)
+	server.OnShutdown(services.Stream.Close)
+	server.AddCloser("tracing", shutdownTracing)
+	server.AddCloser("database", func(context.Context) error {
+		return db.Close()
+	})
 
-}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-type handlerLog struct {
-	logger *zap.Logger
+	logger.Info("task manager started")
+	if err := server.Run(ctx); err != nil {
+		logger.Error("task manager stopped with error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("task manager stopped")
 }
 
-func (h handlerLog) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
-	h.logger.Info(
-		"My log message",
-		zap.String("my-key", "my-value"),
-	)
+func rateLimitsFromEnv() (handler.RateLimits, error) {
+	var rateLimits handler.RateLimits
+	var err error
+
+	if rateLimits.Default.Principal, err = ratelimit.ParseLimit(envString("RATE_LIMIT_PRINCIPAL", defaultPrincipalRateLimit)); err != nil {
+		return rateLimits, err
+	}
+	if rateLimits.Default.Telegram, err = ratelimit.ParseLimit(envString("RATE_LIMIT_TELEGRAM", defaultTelegramRateLimit)); err != nil {
+		return rateLimits, err
+	}
+	for _, proxy := range envList("TRUSTED_PROXIES") {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return rateLimits, fmt.Errorf("invalid TRUSTED_PROXIES: %q is not an address or CIDR", proxy)
+		}
+		rateLimits.TrustedProxies = append(rateLimits.TrustedProxies, proxy)
+	}
+	rateLimits.Routes, err = ratelimit.ParseRoutes(envString("RATE_LIMIT_ROUTES", defaultRouteRateLimits))
+	return rateLimits, err
+}
 
-	writer.WriteHeader(200)
-	_, _ = writer.Write([]byte("Hello, world!"))
+func quotasFromEnv() (service.QuotaConfig, error) {
+	quotas := service.QuotaConfig{
+		DefaultTier: envString("QUOTA_DEFAULT_TIER", defaultQuotaTier),
+	}
+	if err := json.Unmarshal([]byte(envString("QUOTA_TIERS", defaultQuotaTiers)), &quotas.Tiers); err != nil {
+		return quotas, fmt.Errorf("invalid QUOTA_TIERS: %w", err)
+	}
+	if _, ok := quotas.Tiers[quotas.DefaultTier]; !ok {
+		return quotas, fmt.Errorf("QUOTA_DEFAULT_TIER %q is not defined in QUOTA_TIERS", quotas.DefaultTier)
+	}
+	return quotas, nil
 }
@@ -0,0 +1,18 @@
+package task_manager
+
+// TaskDependency means the task cannot start until BlockedById is done.
+type TaskDependency struct {
+	TaskId      int `json:"task_id" db:"task_id"`
+	BlockedById int `json:"blocked_by_id" db:"blocked_by_id"`
+}
+
+type TaskDependencyInput struct {
+	BlockedById int `form:"blocked_by_id" json:"blocked_by_id" binding:"required"`
+}
+
+// DependencyGraph holds the tasks of a list, their blockers from other
+// lists and the dependencies between them.
+type DependencyGraph struct {
+	Nodes []Task           `json:"nodes"`
+	Edges []TaskDependency `json:"edges"`
+}
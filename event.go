@@ -0,0 +1,65 @@
+package task_manager
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTaskCreated     EventType = "task.created"
+	EventTaskUpdated     EventType = "task.updated"
+	EventTaskCompleted   EventType = "task.completed"
+	EventTaskDeleted     EventType = "task.deleted"
+	EventReminderCreated EventType = "reminder.created"
+	EventReminderFired   EventType = "reminder.fired"
+	// EventWebhookTest is sent only by the test action of webhooks.
+	EventWebhookTest EventType = "webhook.test"
+)
+
+func (t EventType) Valid() bool {
+	switch t {
+	case EventTaskCreated, EventTaskUpdated, EventTaskCompleted, EventTaskDeleted,
+		EventReminderCreated, EventReminderFired:
+		return true
+	}
+	return false
+}
+
+// Event is a change published through the outbox. Payload holds the task,
+// DeletedTask for task.deleted or, for reminder events, the reminder. Events may be delivered more
+// than once; consumers drop duplicates by Id.
+type Event struct {
+	Id         int64           `json:"id" db:"id"`
+	Type       EventType       `json:"type" db:"event_type"`
+	TelegramId int             `json:"telegram_id" db:"telegram_id"`
+	Payload    json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	Attempts   int             `json:"-" db:"attempts"`
+	// Txid is the id of the transaction that published the event.
+	Txid int64 `json:"-" db:"txid"`
+}
+
+// Position returns where the event is in commit order.
+func (e Event) Position() StreamPosition {
+	return StreamPosition{Txid: e.Txid, Id: e.Id}
+}
+
+// StreamPosition orders events by the transaction that published them and
+// then by id. Unlike ids alone it never lets an event of a transaction that
+// commits later slip in before a position that was already read.
+type StreamPosition struct {
+	Txid int64
+	Id   int64
+}
+
+// Before reports whether p comes before other.
+func (p StreamPosition) Before(other StreamPosition) bool {
+	return p.Txid < other.Txid || p.Txid == other.Txid && p.Id < other.Id
+}
+
+// DeletedTask is the payload of task.deleted events.
+type DeletedTask struct {
+	Id int `json:"id"`
+}
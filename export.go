@@ -0,0 +1,50 @@
+package task_manager
+
+import (
+	"time"
+)
+
+// TaskRecord is a task in exports. Id, ListId, CreatedAt and ArchivedAt
+// describe the exported task and are ignored on import.
+type TaskRecord struct {
+	Id          int        `json:"id"`
+	Text        string     `json:"text"`
+	Status      StatusEnd  `json:"status"`
+	Priority    Priority   `json:"priority"`
+	StartTime   time.Time  `json:"start_time"`
+	DueAt       *time.Time `json:"due_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Tags        []string   `json:"tags"`
+	ListId      *int       `json:"list_id"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+}
+
+func NewTaskRecord(task Task) TaskRecord {
+	record := TaskRecord{
+		Id:         task.Id,
+		Text:       task.Text,
+		Status:     task.StatusEnd,
+		Priority:   task.Priority,
+		StartTime:  task.StartTimeAt,
+		DueAt:      task.DueAt,
+		CreatedAt:  task.CreatedAt,
+		Tags:       task.Tags,
+		ListId:     task.ListId,
+		ArchivedAt: task.ArchivedAt,
+	}
+	if task.StatusEnd == End {
+		record.CompletedAt = task.EndTask
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	return record
+}
+
+// ImportRowError describes an invalid row of an import; Row counts data
+// rows from 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,16 @@
+package task_manager
+
+// IdempotencyRecord is a stored request fingerprint and, once the request
+// finished, the response to replay for retries with the same key.
+type IdempotencyRecord struct {
+	Principal   string  `db:"principal"`
+	Key         string  `db:"key"`
+	Fingerprint string  `db:"fingerprint"`
+	StatusCode  *int    `db:"status_code"`
+	ContentType *string `db:"content_type"`
+	Response    []byte  `db:"response"`
+}
+
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != nil
+}
@@ -0,0 +1,40 @@
+package task_manager
+
+import (
+	"time"
+)
+
+type List struct {
+	Id         int        `json:"id" db:"id"`
+	TelegramId int        `json:"telegram_id" db:"telegram_id"`
+	Title      string     `json:"title" db:"title"`
+	Position   int        `json:"position" db:"position"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at" db:"archived_at"`
+	TaskCount  int        `json:"task_count" db:"task_count"`
+}
+
+type ListInput struct {
+	Title string `form:"title" json:"title" binding:"required"`
+}
+
+type UpdateListInput struct {
+	Title    *string `form:"title" json:"title"`
+	Position *int    `form:"position" json:"position"`
+}
+
+func (i UpdateListInput) Empty() bool {
+	return i.Title == nil && i.Position == nil
+}
+
+// MoveTaskInput moves a task to another list, or out of any list when
+// ListId is null. A missing Position puts the task last.
+type MoveTaskInput struct {
+	ListId   *int `form:"list_id" json:"list_id"`
+	Position *int `form:"position" json:"position"`
+}
+
+// ListOrderInput lists task ids of a list in the wanted order.
+type ListOrderInput struct {
+	TaskIds []int `form:"task_ids" json:"task_ids" binding:"required"`
+}
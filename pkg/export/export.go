@@ -0,0 +1,221 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"task_manager"
+	"time"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// tagSeparator joins tags in a CSV cell; tag names cannot contain spaces.
+const tagSeparator = " "
+
+// Columns is the header of CSV exports.
+var Columns = []string{"id", "text", "status", "priority", "start_time", "due_at", "completed_at", "created_at", "tags", "list_id", "archived_at"}
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+type Writer interface {
+	Write(record task_manager.TaskRecord) error
+	// Flush writes buffered records and reports earlier write errors.
+	Flush() error
+}
+
+// NewWriter returns a writer of records in format. CSV output starts
+// with the header.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := &csvWriter{w: csv.NewWriter(w)}
+		return writer, writer.w.Write(Columns)
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlWriter{w: buffered, enc: json.NewEncoder(buffered)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(record task_manager.TaskRecord) error {
+	return w.w.Write([]string{
+		strconv.Itoa(record.Id),
+		record.Text,
+		string(record.Status),
+		string(record.Priority),
+		formatTime(&record.StartTime),
+		formatTime(record.DueAt),
+		formatTime(record.CompletedAt),
+		formatTime(&record.CreatedAt),
+		strings.Join(record.Tags, tagSeparator),
+		formatInt(record.ListId),
+		formatTime(record.ArchivedAt),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record task_manager.TaskRecord) error {
+	return w.enc.Encode(record)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Row is a decoded record with its number among the data rows, from 1.
+type Row struct {
+	Number int
+	Record task_manager.TaskRecord
+}
+
+// Read decodes all records of r. Rows that cannot be decoded are reported
+// as row errors, while errors reading r are returned.
+func Read(r io.Reader, format string) ([]Row, []task_manager.ImportRowError, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSONL:
+		return readJSONL(r)
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+func readJSONL(r io.Reader) (rows []Row, rowErrors []task_manager.ImportRowError, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			row--
+			continue
+		}
+		var record task_manager.TaskRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			rowErrors = append(rowErrors, task_manager.ImportRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, Row{Number: row, Record: record})
+	}
+	return rows, rowErrors, scanner.Err()
+}
+
+func readCSV(r io.Reader) (rows []Row, rowErrors []task_manager.ImportRowError, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, []task_manager.ImportRowError{{Row: 0, Message: "invalid header: " + parseErr.Error()}}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for row := 1; ; row++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, rowErrors, nil
+		}
+		if errors.As(err, &parseErr) {
+			rowErrors = append(rowErrors, task_manager.ImportRowError{Row: row, Message: parseErr.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record, err := parseCSVRecord(columns, values)
+		if err != nil {
+			rowErrors = append(rowErrors, task_manager.ImportRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, Row{Number: row, Record: record})
+	}
+}
+
+func parseCSVRecord(columns map[string]int, values []string) (record task_manager.TaskRecord, err error) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	record.Text = value("text")
+	record.Status = task_manager.StatusEnd(value("status"))
+	record.Priority = task_manager.Priority(value("priority"))
+	if tags := value("tags"); tags != "" {
+		record.Tags = strings.Fields(tags)
+	}
+	if start := value("start_time"); start != "" {
+		if record.StartTime, err = time.Parse(time.RFC3339, start); err != nil {
+			return record, fmt.Errorf("invalid start_time: %w", err)
+		}
+	}
+	if record.DueAt, err = parseOptionalTime(value("due_at")); err != nil {
+		return record, fmt.Errorf("invalid due_at: %w", err)
+	}
+	if record.CompletedAt, err = parseOptionalTime(value("completed_at")); err != nil {
+		return record, fmt.Errorf("invalid completed_at: %w", err)
+	}
+	return record, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return &t, err
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"task_manager"
+)
+
+// @Summary Batch task operations
+// @Tags tasks
+// @Description create, update, complete or delete tasks of a user, by id or by filter, in one transaction; atomic mode (default) applies all operations or none, best_effort applies the ones that succeed
+// @ID batch-tasks
+// @Accept  json
+// @Produce  json
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param input body task_manager.BatchInput true "operations"
+// @Success 200 {object} task_manager.BatchResult
+// @Failure 400 {object} errorResponse
+// @Failure 403 {object} errorResponse "quota_exceeded"
+// @Failure 409 {object} errorResponse "idempotency key conflict"
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/batch [post]
+func (h *Handler) batchTasks(c *gin.Context) {
+	var input task_manager.BatchInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	result, err := h.services.TaskManagerTask.Batch(c.Request.Context(), input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/ical"
+	"time"
+)
+
+const (
+	calendarProdId = "-//task_manager//tasks//EN"
+	calendarName   = "Tasks"
+
+	maxCalendarImportBytes = 2 << 20
+)
+
+// icalPriorities maps task priorities to the 1 (highest) to 9 scale of RFC 5545.
+var icalPriorities = map[task_manager.Priority]string{
+	task_manager.PriorityUrgent: "1",
+	task_manager.PriorityHigh:   "3",
+	task_manager.PriorityNormal: "5",
+	task_manager.PriorityLow:    "9",
+}
+
+type calendarTokenResponse struct {
+	Token string `json:"token"`
+	// FeedPath is the path of the feed with the token to subscribe to.
+	FeedPath string `json:"feed_path"`
+}
+
+// @Summary Rotate calendar feed token
+// @Tags calendar
+// @Description issue a new secret token of the calendar feed; the previous token stops working
+// @ID rotate-calendar-token
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} calendarTokenResponse
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/calendar/token [post]
+func (h *Handler) rotateCalendarToken(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	token, err := h.services.Calendar.RotateToken(c.Request.Context(), telegramId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarTokenResponse{
+		Token:    token,
+		FeedPath: fmt.Sprintf("/api/telegram/%d/calendar.ics?token=%s", telegramId, token),
+	})
+}
+
+// @Summary Revoke calendar feed token
+// @Tags calendar
+// @Description disable the calendar feed
+// @ID revoke-calendar-token
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/calendar/token [delete]
+func (h *Handler) revokeCalendarToken(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Calendar.RevokeToken(c.Request.Context(), telegramId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+// @Summary Calendar feed
+// @Tags calendar
+// @Description iCalendar feed of the tasks of a user with an alarm at the start time of every unfinished task, as events or, with type=todo, as to-dos
+// @ID get-calendar
+// @Produce  text/calendar
+// @Param id path int true "telegram ID"
+// @Param token query string true "feed token"
+// @Param type query string false "component type, event by default" Enums(event, todo)
+// @Success 200 {string} string "iCalendar data"
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/calendar.ics [get]
+func (h *Handler) getCalendar(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	component := "VEVENT"
+	switch c.Query("type") {
+	case "", "event":
+	case "todo":
+		component = "VTODO"
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "invalid type")
+		return
+	}
+
+	feed, err := h.services.Calendar.Feed(c.Request.Context(), telegramId, c.Query("token"))
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Type", ical.ContentType)
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Status(http.StatusOK)
+	if err := writeCalendar(c.Writer, component, feed); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// @Summary Import calendar
+// @Tags calendar
+// @Description create tasks from the events and to-dos of an uploaded .ics file; components imported before are skipped by UID
+// @ID import-calendar
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param file formData file true "iCalendar file"
+// @Param tz query string false "IANA time zone of floating times, UTC by default"
+// @Success 200 {object} task_manager.CalendarImportReport
+// @Failure 400,413 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/calendar/import [post]
+func (h *Handler) importCalendar(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid tz")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarImportBytes)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			newErrorResponse(c, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		newErrorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "failed to read file")
+		return
+	}
+	defer file.Close()
+
+	calendar, err := ical.Parse(file)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.services.Calendar.Import(c.Request.Context(), telegramId, calendar, loc)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// writeCalendar encodes the tasks of feed as components of a calendar. A
+// series is written as a component with an RRULE, whose occurrences are
+// overridden by the tasks of the series and excluded with EXDATE when they
+// have no task in the feed.
+func writeCalendar(w io.Writer, component string, feed task_manager.CalendarFeed) error {
+	enc := ical.NewEncoder(w)
+	enc.Begin("VCALENDAR")
+	enc.Property("VERSION", "2.0")
+	enc.Property("PRODID", calendarProdId)
+	enc.Property("CALSCALE", "GREGORIAN")
+	enc.Text("X-WR-CALNAME", calendarName)
+
+	seriesTasks := make(map[int][]task_manager.Task, len(feed.Series))
+	for _, series := range feed.Series {
+		seriesTasks[series.Id] = nil
+	}
+	for _, task := range feed.Tasks {
+		if task.SeriesId != nil && task.RecurrenceId != nil {
+			if _, ok := seriesTasks[*task.SeriesId]; ok {
+				seriesTasks[*task.SeriesId] = append(seriesTasks[*task.SeriesId], task)
+				continue
+			}
+		}
+		writeTask(enc, component, task, "")
+	}
+	for _, series := range feed.Series {
+		if tasks := seriesTasks[series.Id]; len(tasks) > 0 {
+			writeSeries(enc, component, series, tasks)
+		}
+	}
+	enc.End("VCALENDAR")
+	return enc.Flush()
+}
+
+// writeSeries writes the recurring component of series followed by its
+// tasks as overrides of their occurrences.
+func writeSeries(enc *ical.Encoder, component string, series task_manager.TaskSeries, tasks []task_manager.Task) {
+	uid := seriesUID(series.Id)
+	first, updatedAt := tasks[0], tasks[0].UpdatedAt
+	overridden := make(map[time.Time]bool, len(tasks))
+	for _, task := range tasks {
+		overridden[task.RecurrenceId.UTC()] = true
+		if task.RecurrenceId.Before(*first.RecurrenceId) {
+			first = task
+		}
+		if task.UpdatedAt.After(updatedAt) {
+			updatedAt = task.UpdatedAt
+		}
+	}
+
+	enc.Begin(component)
+	enc.Property("UID", uid)
+	enc.Time("DTSTAMP", updatedAt)
+	enc.Text("SUMMARY", first.Text)
+	enc.Time("DTSTART", series.StartTimeAt)
+	enc.Property("RRULE", series.Recurrence)
+	for _, occurrence := range series.Occurrences {
+		if !overridden[occurrence.UTC()] {
+			enc.Time("EXDATE", occurrence)
+		}
+	}
+	enc.End(component)
+
+	for _, task := range tasks {
+		writeTask(enc, component, task, uid)
+	}
+}
+
+// writeTask writes a task, as the override of its occurrence when seriesUID
+// is the UID of its series.
+func writeTask(enc *ical.Encoder, component string, task task_manager.Task, seriesUID string) {
+	enc.Begin(component)
+	if seriesUID != "" {
+		enc.Property("UID", seriesUID)
+		enc.Time("RECURRENCE-ID", *task.RecurrenceId)
+	} else {
+		enc.Property("UID", taskUID(task.Id))
+	}
+	enc.Time("DTSTAMP", task.UpdatedAt)
+	enc.Time("CREATED", task.CreatedAt)
+	enc.Time("LAST-MODIFIED", task.UpdatedAt)
+	enc.Property("SEQUENCE", strconv.Itoa(max(task.Version-1, 0)))
+	enc.Text("SUMMARY", task.Text)
+	enc.Time("DTSTART", task.StartTimeAt)
+	if priority, ok := icalPriorities[task.Priority]; ok {
+		enc.Property("PRIORITY", priority)
+	}
+	if len(task.Tags) > 0 {
+		categories := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			categories[i] = ical.EscapeText(tag)
+		}
+		enc.Property("CATEGORIES", strings.Join(categories, ","))
+	}
+
+	if component == "VTODO" {
+		if task.DueAt != nil && task.DueAt.After(task.StartTimeAt) {
+			enc.Time("DUE", *task.DueAt)
+		}
+		if task.StatusEnd == task_manager.End {
+			enc.Property("STATUS", "COMPLETED")
+			if task.EndTask != nil {
+				enc.Time("COMPLETED", *task.EndTask)
+			}
+		} else {
+			enc.Property("STATUS", "NEEDS-ACTION")
+		}
+	} else if task.DueAt != nil && task.DueAt.After(task.StartTimeAt) {
+		enc.Time("DTEND", *task.DueAt)
+	}
+
+	if task.StatusEnd == task_manager.Start {
+		enc.Begin("VALARM")
+		enc.Property("ACTION", "DISPLAY")
+		enc.Text("DESCRIPTION", task.Text)
+		enc.Property("TRIGGER;RELATED=START", "PT0S")
+		enc.End("VALARM")
+	}
+	enc.End(component)
+}
+
+func taskUID(taskId int) string {
+	return "task-" + strconv.Itoa(taskId) + "@" + serviceName
+}
+
+func seriesUID(seriesId int) string {
+	return "series-" + strconv.Itoa(seriesId) + "@" + serviceName
+}
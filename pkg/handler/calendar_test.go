@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"strings"
+	"task_manager"
+	"testing"
+	"time"
+)
+
+func TestWriteCalendarSeries(t *testing.T) {
+	at := func(day int) *time.Time {
+		t := time.Date(2026, 3, day, 8, 0, 0, 0, time.UTC)
+		return &t
+	}
+	seriesId := 7
+	task := func(id, day int, start *time.Time) task_manager.Task {
+		return task_manager.Task{Id: id, Text: "task", StartTimeAt: *start, StatusEnd: task_manager.Start,
+			Version: 1, SeriesId: &seriesId, RecurrenceId: at(day)}
+	}
+	feed := task_manager.CalendarFeed{
+		Tasks: []task_manager.Task{
+			task(1, 2, at(2)),
+			// Moved to the next day, so it keeps its recurrence id.
+			task(3, 4, at(5)),
+			{Id: 4, Text: "single", StartTimeAt: *at(2), StatusEnd: task_manager.Start, Version: 1},
+		},
+		Series: []task_manager.TaskSeries{{
+			Id:          seriesId,
+			Recurrence:  "FREQ=DAILY;INTERVAL=1;COUNT=3",
+			StartTimeAt: *at(2),
+			Occurrences: []time.Time{*at(2), *at(3), *at(4)},
+		}},
+	}
+
+	var b strings.Builder
+	if err := writeCalendar(&b, "VEVENT", feed); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, line := range []string{
+		"UID:series-7@" + serviceName + "\r\n",
+		"RRULE:FREQ=DAILY;INTERVAL=1;COUNT=3\r\n",
+		"EXDATE:20260303T080000Z\r\n",
+		"RECURRENCE-ID:20260302T080000Z\r\n",
+		"RECURRENCE-ID:20260304T080000Z\r\n",
+		"DTSTART:20260305T080000Z\r\n",
+		"UID:task-4@" + serviceName + "\r\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("calendar misses %q:\n%s", line, got)
+		}
+	}
+	if n := strings.Count(got, "BEGIN:VEVENT"); n != 4 {
+		t.Errorf("calendar has %d events, want 4", n)
+	}
+	for _, line := range []string{"EXDATE:20260302T080000Z", "EXDATE:20260304T080000Z", "UID:task-1@", "UID:task-3@"} {
+		if strings.Contains(got, line) {
+			t.Errorf("calendar has %q:\n%s", line, got)
+		}
+	}
+}
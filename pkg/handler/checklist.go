@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getChecklistResponse struct {
+	Data []task_manager.ChecklistItem `json:"data"`
+}
+
+// @Summary Get checklist
+// @Tags checklist
+// @Description get checklist items of a task ordered by position
+// @ID get-checklist
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Success 200 {object} getChecklistResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist [get]
+func (h *Handler) getChecklist(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	items, err := h.services.Checklist.GetAll(c.Request.Context(), taskId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getChecklistResponse{
+		Data: items,
+	})
+}
+
+// @Summary Create checklist item
+// @Tags checklist
+// @Description add an item to the checklist of a task
+// @ID create-checklist-item
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param input body task_manager.ChecklistItemInput true "item info"
+// @Success 200 {object} task_manager.ChecklistItem
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist [post]
+func (h *Handler) createChecklistItem(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.ChecklistItemInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	item, err := h.services.Checklist.Create(c.Request.Context(), taskId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Update checklist item
+// @Tags checklist
+// @Description change the text or done state of an item; when the last item is done a task with checklist_auto_complete is completed
+// @ID update-checklist-item
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param item_id path int true "Item ID"
+// @Param input body task_manager.UpdateChecklistItemInput true "fields to update"
+// @Success 200 {object} task_manager.ChecklistItem
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist/{item_id} [put]
+func (h *Handler) updateChecklistItem(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	itemId, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid item_id param")
+		return
+	}
+	var input task_manager.UpdateChecklistItemInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	item, err := h.services.Checklist.Update(c.Request.Context(), taskId, itemId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Toggle checklist item
+// @Tags checklist
+// @Description flip the done state of an item
+// @ID toggle-checklist-item
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} task_manager.ChecklistItem
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist/{item_id}/toggle [post]
+func (h *Handler) toggleChecklistItem(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	itemId, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid item_id param")
+		return
+	}
+
+	item, err := h.services.Checklist.Toggle(c.Request.Context(), taskId, itemId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Reorder checklist
+// @Tags checklist
+// @Description set the order of checklist items; item_ids must contain every item of the task once
+// @ID reorder-checklist
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param input body task_manager.ChecklistOrderInput true "item ids in the wanted order"
+// @Success 200 {object} getChecklistResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist/order [put]
+func (h *Handler) reorderChecklist(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.ChecklistOrderInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	items, err := h.services.Checklist.Reorder(c.Request.Context(), taskId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getChecklistResponse{
+		Data: items,
+	})
+}
+
+// @Summary Delete checklist item
+// @Tags checklist
+// @Description delete checklist item
+// @ID delete-checklist-item
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/checklist/{item_id} [delete]
+func (h *Handler) deleteChecklistItem(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	itemId, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid item_id param")
+		return
+	}
+
+	if err := h.services.Checklist.Delete(c.Request.Context(), taskId, itemId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getBlockersResponse struct {
+	Data []task_manager.Task `json:"data"`
+}
+
+// @Summary Get blockers
+// @Tags dependencies
+// @Description get the tasks a task is blocked by
+// @ID get-blockers
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Success 200 {object} getBlockersResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/dependencies [get]
+func (h *Handler) getBlockers(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	blockers, err := h.services.Dependency.GetBlockers(c.Request.Context(), taskId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getBlockersResponse{
+		Data: blockers,
+	})
+}
+
+// @Summary Add dependency
+// @Tags dependencies
+// @Description block a task by another task of the same user; its reminders wait until the blocker is done
+// @ID add-dependency
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param input body task_manager.TaskDependencyInput true "blocking task"
+// @Success 200 {object} task_manager.Task
+// @Failure 400,404 {object} errorResponse
+// @Failure 409 {object} errorResponse "dependency_cycle or already_exists"
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/dependencies [post]
+func (h *Handler) addDependency(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.TaskDependencyInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	task, err := h.services.Dependency.Add(c.Request.Context(), taskId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Remove dependency
+// @Tags dependencies
+// @Description unblock a task from another task
+// @ID remove-dependency
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param blocked_by_id path int true "Blocking task ID"
+// @Success 200 {object} task_manager.Task
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/dependencies/{blocked_by_id} [delete]
+func (h *Handler) removeDependency(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	blockedById, err := strconv.Atoi(c.Param("blocked_by_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid blocked_by_id param")
+		return
+	}
+
+	task, err := h.services.Dependency.Remove(c.Request.Context(), taskId, blockedById)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Get list dependency graph
+// @Tags dependencies
+// @Description get the tasks of a list as graph nodes and their dependencies as edges; tasks of other lists connected to the list are included
+// @ID get-list-graph
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Success 200 {object} task_manager.DependencyGraph
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id}/graph [get]
+func (h *Handler) getListGraph(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	graph, err := h.services.Dependency.GetListGraph(c.Request.Context(), listId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
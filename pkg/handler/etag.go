@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"task_manager"
+)
+
+const (
+	etagHeader        = "ETag"
+	ifMatchHeader     = "If-Match"
+	ifNoneMatchHeader = "If-None-Match"
+
+	preconditionRequiredCode = "precondition_required"
+)
+
+// taskETag is the version of the task followed by a hash of the computed
+// overdue and blocked flags, the tags and the dependencies, which change
+// without a new version. If-Match only compares the version.
+func taskETag(task task_manager.Task) string {
+	hash := sha1.New()
+	writeTaskState(hash, task)
+	return `"` + strconv.Itoa(task.Version) + "-" + hex.EncodeToString(hash.Sum(nil))[:16] + `"`
+}
+
+// tasksETag is a weak validator of a task list built from ids, versions
+// and the state taskETag hashes.
+func tasksETag(tasks []task_manager.Task) string {
+	hash := sha1.New()
+	for _, task := range tasks {
+		writeTaskState(hash, task)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+func writeTaskState(w io.Writer, task task_manager.Task) {
+	fmt.Fprintf(w, "%d:%d:%t:%t:%v:%v;", task.Id, task.Version, task.Overdue, task.Blocked, task.Tags, task.BlockedBy)
+}
+
+// requireVersion reads the task version from If-Match, ignoring the state
+// hash of taskETag. "*" matches any version and yields zero. A missing or
+// malformed header aborts the request with 428 and ok is false.
+func requireVersion(c *gin.Context) (version int, ok bool) {
+	header := strings.TrimSpace(c.GetHeader(ifMatchHeader))
+	if header == "*" {
+		return 0, true
+	}
+	value, _, _ := strings.Cut(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), "-")
+	version, err := strconv.Atoi(value)
+	if header == "" || err != nil || version <= 0 {
+		newCodedErrorResponse(c, http.StatusPreconditionRequired, preconditionRequiredCode,
+			"If-Match header with the task ETag is required")
+		return 0, false
+	}
+	return version, true
+}
+
+// notModified sets the ETag and answers 304 when If-None-Match already
+// holds it, using the weak comparison of RFC 9110.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header(etagHeader, etag)
+
+	header := c.GetHeader(ifNoneMatchHeader)
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"task_manager"
+	"testing"
+)
+
+func TestRequireVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	etag := taskETag(task_manager.Task{Id: 1, Version: 7})
+	tests := []struct {
+		header string
+		want   int
+		wantOk bool
+	}{
+		{header: etag, want: 7, wantOk: true},
+		{header: "W/" + etag, want: 7, wantOk: true},
+		{header: `"7"`, want: 7, wantOk: true},
+		{header: "7", want: 7, wantOk: true},
+		{header: "*", want: 0, wantOk: true},
+		{header: " * ", want: 0, wantOk: true},
+		{header: ""},
+		{header: `"0-abc"`},
+		{header: `"-1"`},
+		{header: `"x-abc"`},
+		{header: `"7", "8"`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/api/tasks/1", nil)
+		if tt.header != "" {
+			c.Request.Header.Set(ifMatchHeader, tt.header)
+		}
+		version, ok := requireVersion(c)
+		if version != tt.want || ok != tt.wantOk {
+			t.Errorf("requireVersion(%q) = %d, %v, want %d, %v", tt.header, version, ok, tt.want, tt.wantOk)
+		}
+		if !ok && w.Code != http.StatusPreconditionRequired {
+			t.Errorf("requireVersion(%q) answered %d, want %d", tt.header, w.Code, http.StatusPreconditionRequired)
+		}
+	}
+}
+
+func TestTaskETag(t *testing.T) {
+	task := task_manager.Task{Id: 1, Version: 3, Tags: []string{"work"}}
+	etag := taskETag(task)
+
+	for name, change := range map[string]func(*task_manager.Task){
+		"version":    func(t *task_manager.Task) { t.Version = 4 },
+		"overdue":    func(t *task_manager.Task) { t.Overdue = true },
+		"blocked":    func(t *task_manager.Task) { t.Blocked = true },
+		"tags":       func(t *task_manager.Task) { t.Tags = []string{"home"} },
+		"blocked_by": func(t *task_manager.Task) { t.BlockedBy = []int64{2} },
+	} {
+		other := task
+		change(&other)
+		if taskETag(other) == etag {
+			t.Errorf("ETag did not change with %s", name)
+		}
+	}
+	if got := taskETag(task); got != etag {
+		t.Errorf("ETag of the same task = %s, want %s", got, etag)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const etag = `W/"abc"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: etag, want: true},
+		{header: `"abc"`, want: true},
+		{header: `"other", W/"abc"`, want: true},
+		{header: "*", want: true},
+		{header: `"other"`, want: false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/tasks/1", nil)
+		if tt.header != "" {
+			c.Request.Header.Set(ifNoneMatchHeader, tt.header)
+		}
+		if got := notModified(c, etag); got != tt.want {
+			t.Errorf("notModified(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+		if got := w.Header().Get(etagHeader); got != etag {
+			t.Errorf("ETag = %q, want %q", got, etag)
+		}
+	}
+}
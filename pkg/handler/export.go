@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+	"task_manager/pkg/export"
+	"task_manager/pkg/service"
+)
+
+const (
+	maxTaskImportBytes = 8 << 20
+	invalidRowsCode    = "invalid_rows"
+)
+
+type importResponse struct {
+	Imported int   `json:"imported"`
+	Ids      []int `json:"ids"`
+}
+
+type importErrorResponse struct {
+	Message string                        `json:"message"`
+	Code    string                        `json:"code"`
+	Rows    []task_manager.ImportRowError `json:"rows"`
+}
+
+// @Summary Export tasks
+// @Tags export
+// @Description stream all tasks of the user, including completed and archived ones, as CSV or JSON Lines
+// @ID export-tasks
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param id path int true "telegram ID"
+// @Param format query string false "csv or jsonl, jsonl by default"
+// @Success 200 {array} task_manager.TaskRecord
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/export [get]
+func (h *Handler) exportTasks(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatJSONL)
+	if format != export.FormatCSV && format != export.FormatJSONL {
+		newErrorResponse(c, http.StatusBadRequest, "invalid format")
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, format))
+	c.Status(http.StatusOK)
+	if err := h.services.Export.Export(c.Request.Context(), telegramId, c.Writer, format); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// @Summary Import tasks
+// @Tags export
+// @Description create tasks from an uploaded CSV or JSON Lines file in the export format; nothing is imported if any row is invalid
+// @ID import-tasks
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param file formData file true "CSV or JSON Lines file"
+// @Param format query string false "csv or jsonl, jsonl by default"
+// @Success 200 {object} importResponse
+// @Failure 400 {object} importErrorResponse
+// @Failure 403 {object} errorResponse "quota_exceeded"
+// @Failure 413 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/import [post]
+func (h *Handler) importTasks(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTaskImportBytes)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			newErrorResponse(c, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		newErrorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "failed to read file")
+		return
+	}
+	defer file.Close()
+
+	ids, err := h.services.Export.Import(c.Request.Context(), telegramId, file, c.DefaultQuery("format", export.FormatJSONL))
+	var importErr *service.ImportError
+	if errors.As(err, &importErr) {
+		_ = c.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, importErrorResponse{
+			Message: err.Error(),
+			Code:    invalidRowsCode,
+			Rows:    importErr.Rows,
+		})
+		return
+	}
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, importResponse{Imported: len(ids), Ids: ids})
+}
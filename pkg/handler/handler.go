@@ -4,34 +4,138 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"log/slog"
+	"net/http"
+	"strings"
 	_ "task_manager/docs"
+	"task_manager/pkg/metrics"
 	"task_manager/pkg/service"
 )
 
+const serviceName = "task_manager"
+
 type Handler struct {
-	services *service.Service
+	services   *service.Service
+	metrics    *metrics.Metrics
+	logger     *slog.Logger
+	rateLimits RateLimits
+	// socketOrigins are the origins, like https://example.com, allowed to
+	// open WebSockets besides the origin of the API.
+	socketOrigins []string
 }
 
-func NewHandler(services *service.Service) *Handler {
-	return &Handler{services: services}
+func NewHandler(services *service.Service, metrics *metrics.Metrics, logger *slog.Logger, rateLimits RateLimits, socketOrigins []string) *Handler {
+	return &Handler{services: services, metrics: metrics, logger: logger, rateLimits: rateLimits, socketOrigins: socketOrigins}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	if err := router.SetTrustedProxies(h.rateLimits.TrustedProxies); err != nil {
+		h.logger.Error("invalid trusted proxies, client addresses are taken from connections", "error", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+	router.Use(otelgin.Middleware(serviceName, otelgin.WithFilter(traced)), h.requestId, h.accessLog, h.measure)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(h.metrics.Handler()))
 
-	api := router.Group("/api")
+	api := router.Group("/api", h.rateLimit)
 	{
 		tasks := api.Group("/tasks")
 		{
-			tasks.POST("/", h.createTask)
+			tasks.POST("/", h.idempotent, h.createTask)
+			tasks.POST("/batch", h.idempotent, h.batchTasks)
 			tasks.DELETE("/:id", h.deleteTask)
 			tasks.GET("/:id", h.getTaskById)
+			tasks.PUT("/:id", h.updateTask)
+			tasks.POST("/:id/complete", h.completeTask)
+			tasks.POST("/:id/move", h.moveTask)
+
+			reminders := tasks.Group("/:id/reminders")
+			{
+				reminders.GET("", h.getReminders)
+				reminders.POST("", h.createReminder)
+				reminders.PUT("/:reminder_id", h.updateReminder)
+				reminders.DELETE("/:reminder_id", h.deleteReminder)
+			}
+
+			checklist := tasks.Group("/:id/checklist")
+			{
+				checklist.GET("", h.getChecklist)
+				checklist.POST("", h.createChecklistItem)
+				checklist.PUT("/order", h.reorderChecklist)
+				checklist.PUT("/:item_id", h.updateChecklistItem)
+				checklist.POST("/:item_id/toggle", h.toggleChecklistItem)
+				checklist.DELETE("/:item_id", h.deleteChecklistItem)
+			}
+
+			dependencies := tasks.Group("/:id/dependencies")
+			{
+				dependencies.GET("", h.getBlockers)
+				dependencies.POST("", h.addDependency)
+				dependencies.DELETE("/:blocked_by_id", h.removeDependency)
+			}
 		}
 		telegram := api.Group("/telegram")
 		{
 			telegram.GET("/:id", h.getTasksByTelegramId)
+			telegram.GET("/:id/quota", h.getQuota)
+			telegram.GET("/:id/tags", h.getTags)
+			telegram.POST("/:id/tags", h.createTag)
+			telegram.GET("/:id/lists", h.getLists)
+			telegram.POST("/:id/lists", h.createList)
+			telegram.GET("/:id/templates", h.getTemplates)
+			telegram.POST("/:id/templates", h.createTemplate)
+			telegram.GET("/:id/calendar.ics", h.getCalendar)
+			telegram.POST("/:id/calendar/token", h.rotateCalendarToken)
+			telegram.DELETE("/:id/calendar/token", h.revokeCalendarToken)
+			telegram.POST("/:id/calendar/import", h.importCalendar)
+			telegram.GET("/:id/export", h.exportTasks)
+			telegram.POST("/:id/import", h.importTasks)
+			telegram.GET("/:id/webhooks", h.getWebhooks)
+			telegram.POST("/:id/webhooks", h.createWebhook)
+			telegram.GET("/:id/events", h.streamEvents)
+			telegram.GET("/:id/ws", h.taskSocket)
+		}
+		lists := api.Group("/lists")
+		{
+			lists.GET("/:id", h.getListById)
+			lists.PUT("/:id", h.updateList)
+			lists.DELETE("/:id", h.deleteList)
+			lists.POST("/:id/archive", h.archiveList)
+			lists.POST("/:id/unarchive", h.unarchiveList)
+			lists.PUT("/:id/order", h.reorderList)
+			lists.GET("/:id/graph", h.getListGraph)
+		}
+		templates := api.Group("/templates")
+		{
+			templates.GET("/:id", h.getTemplateById)
+			templates.PUT("/:id", h.updateTemplate)
+			templates.DELETE("/:id", h.deleteTemplate)
+			templates.POST("/:id/instantiate", h.instantiateTemplate)
+		}
+		tags := api.Group("/tags")
+		{
+			tags.PUT("/:id", h.renameTag)
+			tags.DELETE("/:id", h.deleteTag)
+			tags.POST("/:id/merge", h.mergeTags)
+		}
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("/:id", h.getWebhookById)
+			webhooks.PUT("/:id", h.updateWebhook)
+			webhooks.DELETE("/:id", h.deleteWebhook)
+			webhooks.GET("/:id/deliveries", h.getWebhookDeliveries)
+			webhooks.POST("/:id/test", h.testWebhook)
 		}
 	}
 	return router
 }
+
+// traced excludes scrapes of the metrics endpoint from tracing, as well as
+// calendar feeds, whose secret tokens are in the query string, and event
+// streams and sockets, whose spans would last as long as the connection.
+func traced(r *http.Request) bool {
+	return r.URL.Path != "/metrics" && !strings.HasSuffix(r.URL.Path, "/calendar.ics") &&
+		!strings.HasSuffix(r.URL.Path, "/events") && !strings.HasSuffix(r.URL.Path, "/ws")
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"task_manager/pkg/service"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+	// idempotencyStoreTimeout bounds storing the outcome of a request, which
+	// is done even when the client is gone so the key does not stay in flight.
+	idempotencyStoreTimeout = 5 * time.Second
+
+	idempotencyKeyReusedCode   = "idempotency_key_reused"
+	idempotencyKeyInFlightCode = "idempotency_key_in_flight"
+)
+
+// responseRecorder copies the response body so it can be stored for replay.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent replays the stored response for requests repeating an
+// Idempotency-Key with the same body and rejects reuse of a key with a
+// different one. Requests without the header pass through.
+func (h *Handler) idempotent(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		newErrorResponse(c, http.StatusBadRequest, "idempotency key is too long")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBodyBytes+1))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	if len(body) > maxPeekBodyBytes {
+		newErrorResponse(c, http.StatusRequestEntityTooLarge, "request body is too large")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	ctx := c.Request.Context()
+	client := principal(c)
+	record, err := h.services.Idempotency.Begin(ctx, client, key, requestFingerprint(c, body))
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		newCodedErrorResponse(c, http.StatusConflict, idempotencyKeyReusedCode, err.Error())
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyInFlight):
+		newCodedErrorResponse(c, http.StatusConflict, idempotencyKeyInFlightCode, err.Error())
+		return
+	case err != nil:
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if record != nil {
+		contentType := gin.MIMEJSON
+		if record.ContentType != nil {
+			contentType = *record.ContentType
+		}
+		c.Header(idempotentReplayHeader, "true")
+		c.Data(*record.StatusCode, contentType, record.Response)
+		c.Abort()
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+	defer cancel()
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		if err := h.services.Idempotency.Release(ctx, client, key); err != nil {
+			h.logger.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+		}
+		return
+	}
+	err = h.services.Idempotency.Complete(ctx, client, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+	}
+}
+
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
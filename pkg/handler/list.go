@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getAllListsResponse struct {
+	Data []task_manager.List `json:"data"`
+}
+
+// @Summary Get lists
+// @Tags lists
+// @Description get lists of a user ordered by position
+// @ID get-lists
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param archived query bool false "include archived lists"
+// @Success 200 {object} getAllListsResponse
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/lists [get]
+func (h *Handler) getLists(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	lists, err := h.services.List.GetAll(c.Request.Context(), telegramId, c.Query("archived") == "true")
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllListsResponse{
+		Data: lists,
+	})
+}
+
+// @Summary Create list
+// @Tags lists
+// @Description create a list after the other lists of the user
+// @ID create-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param input body task_manager.ListInput true "list info"
+// @Success 200 {object} task_manager.List
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/lists [post]
+func (h *Handler) createList(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.ListInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	list, err := h.services.List.Create(c.Request.Context(), telegramId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get list By Id
+// @Tags lists
+// @Description get list by id
+// @ID get-list-by-id
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Success 200 {object} task_manager.List
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id} [get]
+func (h *Handler) getListById(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	list, err := h.services.List.GetById(c.Request.Context(), listId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Update list
+// @Tags lists
+// @Description rename a list or change its position
+// @ID update-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Param input body task_manager.UpdateListInput true "fields to update"
+// @Success 200 {object} task_manager.List
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id} [put]
+func (h *Handler) updateList(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.UpdateListInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	list, err := h.services.List.Update(c.Request.Context(), listId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Delete list
+// @Tags lists
+// @Description delete list; its tasks are kept without a list
+// @ID delete-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id} [delete]
+func (h *Handler) deleteList(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.List.Delete(c.Request.Context(), listId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+// @Summary Archive list
+// @Tags lists
+// @Description archive a list with all its tasks; their reminders stop firing
+// @ID archive-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Success 200 {object} task_manager.List
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id}/archive [post]
+func (h *Handler) archiveList(c *gin.Context) {
+	h.setListArchived(c, true)
+}
+
+// @Summary Unarchive list
+// @Tags lists
+// @Description restore an archived list with all its tasks
+// @ID unarchive-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Success 200 {object} task_manager.List
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id}/unarchive [post]
+func (h *Handler) unarchiveList(c *gin.Context) {
+	h.setListArchived(c, false)
+}
+
+func (h *Handler) setListArchived(c *gin.Context, archived bool) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	list, err := h.services.List.Archive(c.Request.Context(), listId, archived)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Reorder list
+// @Tags lists
+// @Description set the order of tasks in a list; task_ids must contain every task of the list once
+// @ID reorder-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "List ID"
+// @Param input body task_manager.ListOrderInput true "task ids in the wanted order"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/lists/{id}/order [put]
+func (h *Handler) reorderList(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.ListOrderInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.services.List.Reorder(c.Request.Context(), listId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
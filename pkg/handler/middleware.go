@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"strconv"
+	"strings"
+	"task_manager/pkg/logging"
+	"time"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	requestIdHeader     = "X-Request-ID"
+	maxRequestIdLength  = 128
+	unmatchedRoute      = "unmatched"
+)
+
+// requestId takes the request id from the X-Request-ID header or generates
+// a new one, returns it to the client and stores it in the request context.
+func (h *Handler) requestId(c *gin.Context) {
+	requestId := c.GetHeader(requestIdHeader)
+	if !validRequestId(requestId) {
+		requestId = newRequestId()
+	}
+
+	c.Header(requestIdHeader, requestId)
+	ctx := logging.WithRequestId(c.Request.Context(), requestId)
+	c.Request = c.Request.WithContext(ctx)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", requestId))
+
+	c.Next()
+}
+
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range requestId {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// principal identifies the API client by a hash of its bearer token or,
+// when the request has none, by its address. Tokens are not verified, so
+// it only scopes data like idempotency keys and must not be used to limit
+// clients.
+func principal(c *gin.Context) string {
+	token, ok := strings.CutPrefix(c.GetHeader(authorizationHeader), "Bearer ")
+	if ok && token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func (h *Handler) accessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	status := c.Writer.Status()
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", c.Request.Method),
+		slog.String("route", c.FullPath()),
+		slog.String("path", c.Request.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", c.ClientIP()),
+		slog.Int("bytes", c.Writer.Size()),
+	}
+	if len(c.Errors) > 0 {
+		attrs = append(attrs, slog.String("error", c.Errors.String()))
+	}
+	h.logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+}
+
+func (h *Handler) measure(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	method := c.Request.Method
+	h.metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	h.metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
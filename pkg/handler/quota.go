@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const quotaExceededCode = "quota_exceeded"
+
+// @Summary Get quota usage
+// @Tags quota
+// @Description get quota limits and usage of a telegram user
+// @ID get-quota
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} task_manager.QuotaUsage
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/quota [get]
+func (h *Handler) getQuota(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	usage, err := h.services.Quota.GetUsage(c.Request.Context(), telegramId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
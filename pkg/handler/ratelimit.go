@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"task_manager/pkg/ratelimit"
+)
+
+const maxPeekBodyBytes = 1 << 20 // 1 MB
+
+type RateLimits struct {
+	Store   ratelimit.Store
+	Default ratelimit.Rule
+	// Routes overrides Default for routes keyed by "<METHOD> <route template>".
+	Routes map[string]ratelimit.Rule
+	// TrustedProxies are the addresses or CIDRs of proxies whose
+	// X-Forwarded-For header gives the client address. Without them the
+	// header is ignored, so clients cannot pick the address they are
+	// limited by.
+	TrustedProxies []string
+}
+
+func (r RateLimits) rule(method, route string) ratelimit.Rule {
+	if rule, ok := r.Routes[method+" "+route]; ok {
+		return rule
+	}
+	return r.Default
+}
+
+// rateLimit applies token bucket limits per client address and per
+// telegram user and reports the most restrictive bucket in X-RateLimit-*
+// headers. Bearer tokens are not verified, so they cannot key the client
+// bucket: a client would get a fresh bucket with every made-up token. For
+// the same reason every request takes from the client bucket, whatever
+// telegram id it names.
+func (h *Handler) rateLimit(c *gin.Context) {
+	route := c.FullPath()
+	rule := h.rateLimits.rule(c.Request.Method, route)
+
+	var results []ratelimit.Result
+	take := func(kind, key string, limit ratelimit.Limit) bool {
+		if !limit.Enabled() || key == "" {
+			return true
+		}
+		result, err := h.rateLimits.Store.Take(c.Request.Context(), c.Request.Method+" "+route+"|"+kind+":"+key, limit)
+		if err != nil {
+			h.logger.WarnContext(c.Request.Context(), "rate limit store failed", "error", err)
+			return true
+		}
+		results = append(results, result)
+		if !result.Allowed {
+			h.metrics.RateLimited.WithLabelValues(c.Request.Method, route, kind).Inc()
+		}
+		return result.Allowed
+	}
+
+	allowed := take("principal", "ip:"+c.ClientIP(), rule.Principal) &&
+		take("telegram", telegramIdFromRequest(c), rule.Telegram)
+	if len(results) == 0 {
+		c.Next()
+		return
+	}
+
+	result := results[0]
+	for _, r := range results[1:] {
+		if !r.Allowed || r.Remaining < result.Remaining {
+			result = r
+		}
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(result.ResetAfterSeconds()))
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(result.RetryAfterSeconds()))
+		newErrorResponse(c, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+	c.Next()
+}
+
+// telegramIdFromRequest finds the telegram user a request acts for: the id
+// path parameter of /api/telegram routes or the telegram_id field of the
+// request body. The body is restored for the handlers that follow.
+func telegramIdFromRequest(c *gin.Context) string {
+	if strings.HasPrefix(c.FullPath(), "/api/telegram/") {
+		return c.Param("id")
+	}
+	if c.Request.Body == nil || c.Request.Method == http.MethodGet {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBodyBytes))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	if c.ContentType() == gin.MIMEJSON {
+		var input struct {
+			TelegramId json.RawMessage `json:"telegram_id"`
+		}
+		if err := json.Unmarshal(body, &input); err != nil {
+			return ""
+		}
+		return strings.Trim(string(input.TelegramId), `"`)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get("telegram_id")
+}
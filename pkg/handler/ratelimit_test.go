@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"task_manager/pkg/metrics"
+	"task_manager/pkg/ratelimit"
+	"testing"
+)
+
+func newRateLimitedRouter(t *testing.T, rule ratelimit.Rule, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := &Handler{
+		metrics: metrics.NewMetrics(),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rateLimits: RateLimits{
+			Store:          ratelimit.NewMemoryStore(),
+			Default:        rule,
+			TrustedProxies: trustedProxies,
+		},
+	}
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	router.POST("/api/tasks/", h.rateLimit, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+type rateLimitedRequest struct {
+	addr          string
+	token         string
+	forwardedFor  string
+	telegramId    string
+	wantCode      int
+	wantRemaining string
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		rule           ratelimit.Rule
+		trustedProxies []string
+		requests       []rateLimitedRequest
+	}{
+		{
+			name: "client bucket",
+			rule: ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 2}},
+			requests: []rateLimitedRequest{
+				{addr: "192.0.2.1", wantCode: http.StatusNoContent, wantRemaining: "1"},
+				{addr: "192.0.2.1", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "192.0.2.1", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+				{addr: "192.0.2.2", wantCode: http.StatusNoContent, wantRemaining: "1"},
+			},
+		},
+		{
+			name: "made-up tokens share the client bucket",
+			rule: ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 1}},
+			requests: []rateLimitedRequest{
+				{addr: "192.0.2.1", token: "a", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "192.0.2.1", token: "b", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+			},
+		},
+		{
+			name: "telegram ids share the client bucket",
+			rule: ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 1}, Telegram: ratelimit.Limit{Rate: 1, Burst: 5}},
+			requests: []rateLimitedRequest{
+				{addr: "192.0.2.1", telegramId: "1", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "192.0.2.1", telegramId: "2", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+			},
+		},
+		{
+			name: "telegram bucket",
+			rule: ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 10}, Telegram: ratelimit.Limit{Rate: 1, Burst: 1}},
+			requests: []rateLimitedRequest{
+				{addr: "192.0.2.1", telegramId: "1", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "192.0.2.2", telegramId: "1", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+				{addr: "192.0.2.1", telegramId: "2", wantCode: http.StatusNoContent, wantRemaining: "0"},
+			},
+		},
+		{
+			name: "forwarded address from an untrusted client is ignored",
+			rule: ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 1}},
+			requests: []rateLimitedRequest{
+				{addr: "192.0.2.1", forwardedFor: "198.51.100.1", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "192.0.2.1", forwardedFor: "198.51.100.2", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+			},
+		},
+		{
+			name:           "forwarded address from a trusted proxy",
+			rule:           ratelimit.Rule{Principal: ratelimit.Limit{Rate: 1, Burst: 1}},
+			trustedProxies: []string{"10.0.0.0/8"},
+			requests: []rateLimitedRequest{
+				{addr: "10.0.0.1", forwardedFor: "198.51.100.1", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "10.0.0.1", forwardedFor: "198.51.100.2", wantCode: http.StatusNoContent, wantRemaining: "0"},
+				{addr: "10.0.0.2", forwardedFor: "198.51.100.1", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		router := newRateLimitedRouter(t, tt.rule, tt.trustedProxies)
+		for i, request := range tt.requests {
+			body := ""
+			if request.telegramId != "" {
+				body = "telegram_id=" + request.telegramId
+			}
+			r := httptest.NewRequest(http.MethodPost, "/api/tasks/", strings.NewReader(body))
+			r.RemoteAddr = request.addr + ":1234"
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if request.token != "" {
+				r.Header.Set("Authorization", "Bearer "+request.token)
+			}
+			if request.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", request.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != request.wantCode {
+				t.Errorf("%s: request %d: code = %d, want %d", tt.name, i, w.Code, request.wantCode)
+			}
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != request.wantRemaining {
+				t.Errorf("%s: request %d: X-RateLimit-Remaining = %q, want %q", tt.name, i, got, request.wantRemaining)
+			}
+			if w.Header().Get("X-RateLimit-Limit") == "" || w.Header().Get("X-RateLimit-Reset") == "" {
+				t.Errorf("%s: request %d: X-RateLimit-Limit or X-RateLimit-Reset missing", tt.name, i)
+			}
+			retryAfter := w.Header().Get("Retry-After")
+			if request.wantCode == http.StatusTooManyRequests {
+				if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds < 1 {
+					t.Errorf("%s: request %d: Retry-After = %q, want whole seconds", tt.name, i, retryAfter)
+				}
+			} else if retryAfter != "" {
+				t.Errorf("%s: request %d: Retry-After = %q on an allowed request", tt.name, i, retryAfter)
+			}
+		}
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	router := newRateLimitedRouter(t, ratelimit.Rule{}, nil)
+	r := httptest.NewRequest(http.MethodPost, "/api/tasks/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("code = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if got := w.Header().Get("X-RateLimit-Limit"); got != "" {
+		t.Errorf("X-RateLimit-Limit = %q without limits", got)
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getAllRemindersResponse struct {
+	Data []task_manager.TaskReminder `json:"data"`
+}
+
+// bindReminderInput reads the reminder trigger from the request body.
+func bindReminderInput(c *gin.Context) (input task_manager.TaskReminderInput, ok bool) {
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return input, false
+	}
+	if input.RemindAtStr != nil {
+		remindAt, err := parseTime(*input.RemindAtStr)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid remind_at")
+			return input, false
+		}
+		input.RemindAt = &remindAt
+	}
+	return input, true
+}
+
+// @Summary Create reminder
+// @Tags reminders
+// @Description add a reminder to a task, either at remind_at or offset_seconds from the task due or start time
+// @ID create-reminder
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param input body task_manager.TaskReminderInput true "reminder trigger"
+// @Success 200 {object} task_manager.TaskReminder
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/reminders [post]
+func (h *Handler) createReminder(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	input, ok := bindReminderInput(c)
+	if !ok {
+		return
+	}
+
+	reminder, err := h.services.TaskReminder.Create(c.Request.Context(), taskId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// @Summary Get reminders
+// @Tags reminders
+// @Description get all reminders of a task
+// @ID get-reminders
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Success 200 {object} getAllRemindersResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/reminders [get]
+func (h *Handler) getReminders(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	reminders, err := h.services.TaskReminder.GetAll(c.Request.Context(), taskId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllRemindersResponse{
+		Data: reminders,
+	})
+}
+
+// @Summary Update reminder
+// @Tags reminders
+// @Description replace the trigger of a reminder, which fires again at the new time
+// @ID update-reminder
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param reminder_id path int true "Reminder ID"
+// @Param input body task_manager.TaskReminderInput true "reminder trigger"
+// @Success 200 {object} task_manager.TaskReminder
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/reminders/{reminder_id} [put]
+func (h *Handler) updateReminder(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	reminderId, err := strconv.Atoi(c.Param("reminder_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid reminder_id param")
+		return
+	}
+	input, ok := bindReminderInput(c)
+	if !ok {
+		return
+	}
+
+	reminder, err := h.services.TaskReminder.Update(c.Request.Context(), taskId, reminderId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// @Summary Delete reminder
+// @Tags reminders
+// @Description delete reminder
+// @ID delete-reminder
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param reminder_id path int true "Reminder ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/reminders/{reminder_id} [delete]
+func (h *Handler) deleteReminder(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	reminderId, err := strconv.Atoi(c.Param("reminder_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid reminder_id param")
+		return
+	}
+
+	if err := h.services.TaskReminder.Delete(c.Request.Context(), taskId, reminderId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
@@ -1,19 +1,91 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	"log/slog"
+	"net/http"
+	"task_manager/pkg/service"
+)
+
+const (
+	notFoundCode        = "not_found"
+	versionMismatchCode = "version_mismatch"
+	alreadyExistsCode   = "already_exists"
+	listArchivedCode    = "list_archived"
+	dependencyCycleCode = "dependency_cycle"
 )
 
 type errorResponse struct {
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
 }
 
 type statusResponse struct {
 	Status string `json:"status"`
 }
 
+// newErrorResponse aborts the request; the message is logged by the access log.
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
-	slog.Error(message)
-	c.AbortWithStatusJSON(statusCode, errorResponse{message})
+	_ = c.Error(errors.New(message))
+	c.AbortWithStatusJSON(statusCode, errorResponse{Message: message})
+}
+
+// newCodedErrorResponse is newErrorResponse with a machine readable code
+// for errors clients are expected to handle.
+func newCodedErrorResponse(c *gin.Context, statusCode int, code, message string) {
+	_ = c.Error(errors.New(message))
+	c.AbortWithStatusJSON(statusCode, errorResponse{Message: message, Code: code})
+}
+
+// newServiceErrorResponse maps errors returned by services to responses.
+func newServiceErrorResponse(c *gin.Context, err error) {
+	statusCode, code := serviceErrorStatus(err)
+	newCodedErrorResponse(c, statusCode, code, err.Error())
+}
+
+// serviceErrorStatus returns the status code and the machine readable code,
+// if any, of an error returned by services.
+func serviceErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound, notFoundCode
+	case errors.Is(err, service.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, versionMismatchCode
+	case errors.Is(err, service.ErrTagExists):
+		return http.StatusConflict, alreadyExistsCode
+	case errors.Is(err, service.ErrInvalidFeedToken):
+		return http.StatusForbidden, ""
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return http.StatusForbidden, quotaExceededCode
+	case errors.Is(err, service.ErrDependencyCycle):
+		return http.StatusConflict, dependencyCycleCode
+	case errors.Is(err, service.ErrListArchived):
+		return http.StatusConflict, listArchivedCode
+	case errors.Is(err, service.ErrStreamClosed):
+		return http.StatusServiceUnavailable, ""
+	case errors.Is(err, service.ErrNothingToUpdate),
+		errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidSort),
+		errors.Is(err, service.ErrClearDueAt),
+		errors.Is(err, service.ErrInvalidReminder),
+		errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidTagMode),
+		errors.Is(err, service.ErrMergeTag),
+		errors.Is(err, service.ErrForeignList),
+		errors.Is(err, service.ErrForeignTask),
+		errors.Is(err, service.ErrInvalidRecurrence),
+		errors.Is(err, service.ErrInvalidTimeOfDay),
+		errors.Is(err, service.ErrTooManyTasks),
+		errors.Is(err, service.ErrInvalidCalendar),
+		errors.Is(err, service.ErrUnknownFormat),
+		errors.Is(err, service.ErrTooManyRows),
+		errors.Is(err, service.ErrEmptyImport),
+		errors.Is(err, service.ErrInvalidTelegramId),
+		errors.Is(err, service.ErrInvalidBatchMode),
+		errors.Is(err, service.ErrInvalidOrder),
+		errors.Is(err, service.ErrInvalidWebhook):
+		return http.StatusBadRequest, ""
+	default:
+		return http.StatusInternalServerError, ""
+	}
 }
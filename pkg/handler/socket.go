@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"task_manager"
+	"task_manager/pkg/service"
+	"time"
+)
+
+const (
+	socketMaxMessageSize = 64 << 10
+	socketWriteTimeout   = 10 * time.Second
+	// socketPongTimeout closes connections whose client stopped answering
+	// pings, which are sent every socketPingInterval.
+	socketPongTimeout  = 60 * time.Second
+	socketPingInterval = 30 * time.Second
+	socketSendBuffer   = 64
+	// socketTokenParam carries the bearer token of browser clients, which
+	// cannot set headers on WebSocket requests.
+	socketTokenParam = "access_token"
+)
+
+const (
+	socketSubscribe = "subscribe"
+	socketCreate    = "create"
+	socketUpdate    = "update"
+	socketComplete  = "complete"
+	socketAck       = "ack"
+	socketError     = "error"
+	socketConflict  = "conflict"
+	socketEvent     = "event"
+	socketReset     = "reset"
+)
+
+// socketTask holds task fields of create and update messages. Times are
+// in the formats of the REST API.
+type socketTask struct {
+	Text      *string                `json:"text"`
+	StartTime *string                `json:"start_time"`
+	Priority  *task_manager.Priority `json:"priority"`
+	DueAt     *string                `json:"due_at"`
+	// ClearDueAt removes the due time of an updated task.
+	ClearDueAt bool     `json:"clear_due_at"`
+	ListId     *int     `json:"list_id"`
+	Tags       []string `json:"tags"`
+}
+
+// socketRequest is a message of the client. Id is echoed in the reply.
+type socketRequest struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	// ListIds limits a subscription to tasks of the lists; empty means all
+	// events of the user.
+	ListIds     []int       `json:"list_ids"`
+	LastEventId int64       `json:"last_event_id"`
+	TaskId      int         `json:"task_id"`
+	Version     int         `json:"version"`
+	Task        *socketTask `json:"task"`
+}
+
+// socketMessage is a message of the server.
+type socketMessage struct {
+	Type    string              `json:"type"`
+	Id      string              `json:"id,omitempty"`
+	Task    *task_manager.Task  `json:"task,omitempty"`
+	Event   *task_manager.Event `json:"event,omitempty"`
+	Status  int                 `json:"status,omitempty"`
+	Code    string              `json:"code,omitempty"`
+	Message string              `json:"message,omitempty"`
+}
+
+// taskSocket serves a WebSocket connection of a user. Replies and events
+// are written by a single writer goroutine.
+type taskSocket struct {
+	h          *Handler
+	conn       *websocket.Conn
+	telegramId int
+	send       chan socketMessage
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	mu        sync.Mutex
+	lists     []int
+	sub       *service.Subscription
+	closeCode int
+	closeText string
+}
+
+// @Summary Task socket
+// @Tags events
+// @Description WebSocket of a user. Clients send subscribe, create, update and complete messages and receive ack, error and conflict replies with the same id, and event messages for subscribed events. A bearer token is required in the Authorization header or, for browsers, in access_token; browsers may connect only from the API origin and SOCKET_ALLOWED_ORIGINS.
+// @ID task-socket
+// @Param id path int true "telegram ID"
+// @Param access_token query string false "bearer token, if the Authorization header is not set"
+// @Success 101
+// @Failure 400,401,403 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/ws [get]
+func (h *Handler) taskSocket(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	token, _ := strings.CutPrefix(c.GetHeader(authorizationHeader), "Bearer ")
+	if token == "" {
+		token = c.Query(socketTokenParam)
+	}
+	if token == "" {
+		newErrorResponse(c, http.StatusUnauthorized, "bearer token is required")
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.checkSocketOrigin}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied.
+		_ = c.Error(err)
+		return
+	}
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	s := &taskSocket{
+		h:          h,
+		conn:       conn,
+		telegramId: telegramId,
+		send:       make(chan socketMessage, socketSendBuffer),
+		cancel:     cancel,
+		closeCode:  websocket.CloseNormalClosure,
+	}
+	s.run(ctx)
+}
+
+func (s *taskSocket) run(ctx context.Context) {
+	s.wg.Add(1)
+	go s.write(ctx)
+	defer func() {
+		s.cancel()
+		s.wg.Wait()
+		s.mu.Lock()
+		if s.sub != nil {
+			s.sub.Close()
+		}
+		s.mu.Unlock()
+	}()
+
+	s.conn.SetReadLimit(socketMaxMessageSize)
+	_ = s.conn.SetReadDeadline(time.Now().Add(socketPongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(socketPongTimeout))
+	})
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var request socketRequest
+		if err = json.Unmarshal(data, &request); err != nil {
+			s.reply(ctx, socketMessage{Type: socketError, Status: http.StatusBadRequest, Message: "invalid message"})
+			continue
+		}
+		s.handle(ctx, request)
+	}
+}
+
+// write sends queued messages and pings until ctx is done, then closes
+// the connection, which also ends the read loop.
+func (s *taskSocket) write(ctx context.Context) {
+	defer s.wg.Done()
+	defer s.conn.Close()
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			message := websocket.FormatCloseMessage(s.closeCode, s.closeText)
+			s.mu.Unlock()
+			_ = s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(socketWriteTimeout))
+			return
+		case message := <-s.send:
+			_ = s.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			err = s.conn.WriteJSON(message)
+		case <-ping.C:
+			err = s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteTimeout))
+		}
+		if err != nil {
+			s.cancel()
+			return
+		}
+	}
+}
+
+func (s *taskSocket) reply(ctx context.Context, message socketMessage) {
+	select {
+	case s.send <- message:
+	case <-ctx.Done():
+	}
+}
+
+func (s *taskSocket) handle(ctx context.Context, request socketRequest) {
+	var task task_manager.Task
+	var err error
+	switch request.Type {
+	case socketSubscribe:
+		err = s.subscribe(ctx, request)
+		if err == nil {
+			s.reply(ctx, socketMessage{Type: socketAck, Id: request.Id})
+			return
+		}
+	case socketCreate:
+		task, err = s.create(ctx, request)
+	case socketUpdate:
+		task, err = s.update(ctx, request)
+	case socketComplete:
+		if err = s.checkTask(ctx, request); err == nil {
+			task, err = s.h.services.TaskManagerTask.Complete(ctx, request.TaskId, request.Version)
+		}
+	default:
+		s.reply(ctx, socketMessage{Type: socketError, Id: request.Id, Status: http.StatusBadRequest,
+			Message: "unknown message type"})
+		return
+	}
+
+	if errors.Is(err, service.ErrVersionMismatch) {
+		message := socketMessage{Type: socketConflict, Id: request.Id, Code: versionMismatchCode, Message: err.Error()}
+		if current, err := s.h.services.TaskManagerTask.GetById(ctx, request.TaskId); err == nil {
+			message.Task = &current
+		}
+		s.reply(ctx, message)
+		return
+	}
+	if err != nil {
+		var requestErr socketRequestError
+		status, code := serviceErrorStatus(err)
+		if errors.As(err, &requestErr) {
+			status = http.StatusBadRequest
+		}
+		s.reply(ctx, socketMessage{Type: socketError, Id: request.Id, Status: status, Code: code, Message: err.Error()})
+		return
+	}
+	s.reply(ctx, socketMessage{Type: socketAck, Id: request.Id, Task: &task})
+}
+
+// subscribe starts forwarding events or, when already subscribed, only
+// replaces the lists.
+func (s *taskSocket) subscribe(ctx context.Context, request socketRequest) error {
+	s.mu.Lock()
+	s.lists = request.ListIds
+	subscribed := s.sub != nil
+	s.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+
+	sub, err := s.h.services.Stream.Subscribe(ctx, s.telegramId)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sub = sub
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.forward(ctx, sub, request.LastEventId)
+	return nil
+}
+
+// forward replays missed events and passes new ones to the client. A
+// subscription that fell behind or a stopping server closes the socket;
+// clients reconnect with the id of the last event they received.
+func (s *taskSocket) forward(ctx context.Context, sub *service.Subscription, lastEventId int64) {
+	defer s.wg.Done()
+
+	send := func(event task_manager.Event) error {
+		if s.wanted(event) {
+			s.reply(ctx, socketMessage{Type: socketEvent, Event: &event})
+		}
+		return ctx.Err()
+	}
+	if lastEventId > 0 {
+		err := sub.Replay(ctx, lastEventId, send)
+		if errors.Is(err, service.ErrReplayUnavailable) {
+			// The client has to reload its state; new events follow.
+			s.reply(ctx, socketMessage{Type: socketReset})
+			err = nil
+		}
+		if err != nil {
+			s.closeWith(websocket.CloseInternalServerErr, "failed to replay events")
+			return
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				s.closeWith(websocket.CloseGoingAway, "event stream closed")
+				return
+			}
+			_ = send(event)
+		}
+	}
+}
+
+// wanted reports whether the event matches the lists of the subscription.
+// Only task events carry the list; the others are always sent.
+func (s *taskSocket) wanted(event task_manager.Event) bool {
+	s.mu.Lock()
+	lists := s.lists
+	s.mu.Unlock()
+	if len(lists) == 0 {
+		return true
+	}
+	switch event.Type {
+	case task_manager.EventTaskCreated, task_manager.EventTaskUpdated, task_manager.EventTaskCompleted:
+	default:
+		return true
+	}
+	var task struct {
+		ListId *int `json:"list_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &task); err != nil {
+		return false
+	}
+	return task.ListId != nil && slices.Contains(lists, *task.ListId)
+}
+
+func (s *taskSocket) closeWith(code int, text string) {
+	s.mu.Lock()
+	s.closeCode, s.closeText = code, text
+	s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *taskSocket) create(ctx context.Context, request socketRequest) (task task_manager.Task, err error) {
+	fields := request.Task
+	if fields == nil || fields.Text == nil || *fields.Text == "" || fields.StartTime == nil {
+		return task, socketRequestError("create requires task text and start_time")
+	}
+	if fields.ClearDueAt {
+		return task, socketRequestError("create cannot clear due_at")
+	}
+	input := task_manager.CreateTaskInput{
+		Text:       *fields.Text,
+		TelegramId: strconv.Itoa(s.telegramId),
+		ListId:     fields.ListId,
+		Tags:       fields.Tags,
+	}
+	if input.StartTime, err = parseTime(*fields.StartTime); err != nil {
+		return task, socketRequestError("invalid start_time")
+	}
+	if fields.Priority != nil {
+		input.Priority = *fields.Priority
+	}
+	if fields.DueAt != nil {
+		dueAt, err := parseTime(*fields.DueAt)
+		if err != nil {
+			return task, socketRequestError("invalid due_at")
+		}
+		input.DueAt = &dueAt
+	}
+
+	id, err := s.h.services.TaskManagerTask.Create(ctx, input)
+	if err != nil {
+		return task, err
+	}
+	return s.h.services.TaskManagerTask.GetById(ctx, id)
+}
+
+func (s *taskSocket) update(ctx context.Context, request socketRequest) (task task_manager.Task, err error) {
+	fields := request.Task
+	if fields == nil {
+		return task, socketRequestError("update requires task fields")
+	}
+	if fields.ListId != nil || fields.Tags != nil {
+		return task, socketRequestError("update cannot change list_id or tags")
+	}
+	input := task_manager.UpdateTaskInput{Text: fields.Text, Priority: fields.Priority, ClearDueAt: fields.ClearDueAt}
+	if fields.StartTime != nil {
+		startTime, err := parseTime(*fields.StartTime)
+		if err != nil {
+			return task, socketRequestError("invalid start_time")
+		}
+		input.StartTime = &startTime
+	}
+	if fields.DueAt != nil {
+		dueAt, err := parseTime(*fields.DueAt)
+		if err != nil {
+			return task, socketRequestError("invalid due_at")
+		}
+		input.DueAt = &dueAt
+	}
+
+	if err = s.checkTask(ctx, request); err != nil {
+		return task, err
+	}
+	return s.h.services.TaskManagerTask.Update(ctx, request.TaskId, input, request.Version)
+}
+
+// checkTask requires the version the client expects, like If-Match of the
+// REST API, and hides tasks of other users behind ErrNotFound.
+func (s *taskSocket) checkTask(ctx context.Context, request socketRequest) error {
+	if request.Version <= 0 {
+		return socketRequestError("version of the task is required")
+	}
+	task, err := s.h.services.TaskManagerTask.GetById(ctx, request.TaskId)
+	if err != nil {
+		return err
+	}
+	if task.TelegramId != s.telegramId {
+		return service.ErrNotFound
+	}
+	return nil
+}
+
+// checkSocketOrigin lets browsers connect from the origin of the API and
+// the configured origins. The bearer token only has to be present, it is
+// not verified yet, so pages of other origins must not be able to open
+// sockets in the name of a user. Clients without an Origin header are not
+// browsers and are let through.
+func (h *Handler) checkSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return slices.ContainsFunc(h.socketOrigins, func(allowed string) bool {
+		return strings.EqualFold(allowed, u.Scheme+"://"+u.Host)
+	})
+}
+
+// socketRequestError reports a malformed message.
+type socketRequestError string
+
+func (e socketRequestError) Error() string {
+	return string(e)
+}
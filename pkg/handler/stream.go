@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+	"task_manager/pkg/service"
+	"time"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	// streamWriteTimeout replaces the server write timeout, which would
+	// otherwise cut streams off, for every write to a stream.
+	streamWriteTimeout = 10 * time.Second
+	// streamRetry is the reconnection delay suggested to clients, in
+	// milliseconds.
+	streamRetry = 3000
+)
+
+// @Summary Task events
+// @Tags events
+// @Description Server-Sent Events stream of task.created, task.updated, task.completed, task.deleted, reminder.created and reminder.fired events of a user; the SSE id is the event id. With Last-Event-ID (or last_event_id for the first connection) missed events are sent first, or a reset event when they are past the outbox retention. Comments are sent as heartbeats every 15 seconds.
+// @ID stream-events
+// @Produce  text/event-stream
+// @Param id path int true "telegram ID"
+// @Param Last-Event-ID header int false "id of the last received event"
+// @Param last_event_id query int false "id of the last received event, if the header is not set"
+// @Success 200 {array} task_manager.Event
+// @Failure 400 {object} errorResponse
+// @Failure 500,503 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/events [get]
+func (h *Handler) streamEvents(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var lastEventId int64
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value != "" {
+		if lastEventId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid last event id")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	sub, err := h.services.Stream.Subscribe(ctx, telegramId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	stream := newEventWriter(c.Writer)
+	if err = stream.write(fmt.Sprintf("retry: %d\n\n", streamRetry)); err != nil {
+		return
+	}
+	if lastEventId > 0 {
+		err = sub.Replay(ctx, lastEventId, stream.writeEvent)
+		if errors.Is(err, service.ErrReplayUnavailable) {
+			// The client has to reload its state; new events follow.
+			err = stream.write("event: reset\ndata: {}\n\n")
+		}
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			err = stream.writeEvent(event)
+		case <-heartbeat.C:
+			err = stream.write(": heartbeat\n\n")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// eventWriter writes Server-Sent Events, flushing each one.
+type eventWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	return &eventWriter{w: w, controller: http.NewResponseController(w)}
+}
+
+func (w *eventWriter) writeEvent(event task_manager.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return w.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data))
+}
+
+func (w *eventWriter) write(message string) error {
+	err := w.controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err = w.w.Write([]byte(message)); err != nil {
+		return err
+	}
+	return w.controller.Flush()
+}
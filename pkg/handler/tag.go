@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getAllTagsResponse struct {
+	Data []task_manager.Tag `json:"data"`
+}
+
+// @Summary Get tags
+// @Tags tags
+// @Description get all tags of a user with the number of tagged tasks
+// @ID get-tags
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} getAllTagsResponse
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/tags [get]
+func (h *Handler) getTags(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	tags, err := h.services.Tag.GetAll(c.Request.Context(), telegramId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllTagsResponse{
+		Data: tags,
+	})
+}
+
+// @Summary Create tag
+// @Tags tags
+// @Description create a tag without tasks; tags are also created from hashtags in task texts
+// @ID create-tag
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param input body task_manager.TagInput true "tag name"
+// @Success 200 {object} task_manager.Tag
+// @Failure 400,409 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/tags [post]
+func (h *Handler) createTag(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.TagInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	tag, err := h.services.Tag.Create(c.Request.Context(), telegramId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary Rename tag
+// @Tags tags
+// @Description rename a tag and rewrite its hashtag in the texts of tagged tasks
+// @ID rename-tag
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Tag ID"
+// @Param input body task_manager.TagInput true "new tag name"
+// @Success 200 {object} task_manager.Tag
+// @Failure 400,404,409 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tags/{id} [put]
+func (h *Handler) renameTag(c *gin.Context) {
+	tagId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.TagInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	tag, err := h.services.Tag.Rename(c.Request.Context(), tagId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary Merge tags
+// @Tags tags
+// @Description move the tasks of a tag to another tag of the same user and delete it
+// @ID merge-tags
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Tag ID"
+// @Param input body task_manager.MergeTagsInput true "tag to merge into"
+// @Success 200 {object} task_manager.Tag
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tags/{id}/merge [post]
+func (h *Handler) mergeTags(c *gin.Context) {
+	tagId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.MergeTagsInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	tag, err := h.services.Tag.Merge(c.Request.Context(), tagId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary Delete tag
+// @Tags tags
+// @Description delete a tag; task texts keep the hashtag
+// @ID delete-tag
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Tag ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tags/{id} [delete]
+func (h *Handler) deleteTag(c *gin.Context) {
+	tagId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Tag.Delete(c.Request.Context(), tagId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
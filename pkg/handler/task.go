@@ -1,13 +1,11 @@
 package handler
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
-	"io/ioutil"
-	"log/slog"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
 	"task_manager"
 	"time"
 )
@@ -18,52 +16,69 @@ import (
 // @ID create-task
 // @Accept  json
 // @Produce  json
-// @Param input body task_manager.CreateTaskInputModer true "task info"
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param input body task_manager.CreateTaskInput true "task info"
 // @Success 200 {integer} integer 1
-// @Failure 400,401 {object} errorResponse
+// @Failure 400,401,404 {object} errorResponse
+// @Failure 403 {object} errorResponse "quota_exceeded"
+// @Failure 409 {object} errorResponse "idempotency key conflict or list_archived"
+// @Failure 413 {object} errorResponse "body over 1 MB with Idempotency-Key"
 // @Failure 500 {object} errorResponse
 // @Failure default {object} errorResponse
 // @Router /api/tasks [post]
 func (h *Handler) createTask(c *gin.Context) {
-	var err error
-	slog.Info("start create task")
-	jsonDataBytes, err := ioutil.ReadAll(c.Request.Body)
-	slog.Info("data", jsonDataBytes)
-	unquoteJsonData := fmt.Sprintf("%s", jsonDataBytes)
-	slog.Info("unquote", unquoteJsonData)
-	unquoteJsonData = strings.ReplaceAll(unquoteJsonData, "+", " ")
-	unquoteJsonData = strings.ReplaceAll(unquoteJsonData, "%3A", ":")
-	slog.Info("unquote", unquoteJsonData)
-	fields := strings.Split(unquoteJsonData, "&")
-	var input task_manager.CreateTaskInputModeration
-	var inputTh task_manager.CreateTaskInput
-
-	for _, field := range fields {
-		items := strings.Split(field, "=")
-		key := items[0]
-		value := items[1]
-		if key == "telegram_id" {
-			inputTh.TelegramId = value
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	// The bot sends a form body, whatever its Content-Type says.
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid form body")
+		return
+	}
+	inputTh := task_manager.CreateTaskInput{
+		TelegramId: form.Get("telegram_id"),
+		Text:       form.Get("text"),
+		Priority:   task_manager.Priority(form.Get("priority")),
+		Tags:       form["tag"],
+	}
+	if form.Has("start_time") {
+		if inputTh.StartTime, err = parseTime(form.Get("start_time")); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid start_time")
+			return
 		}
-		if key == "text" {
-			inputTh.Text = value
+	}
+	if form.Has("due_at") {
+		dueAt, err := parseTime(form.Get("due_at"))
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid due_at")
+			return
+		}
+		inputTh.DueAt = &dueAt
+	}
+	if form.Has("list_id") {
+		listId, err := strconv.Atoi(form.Get("list_id"))
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid list_id")
+			return
 		}
-		if key == "start_time" {
-			inputTh.StartTime, err = time.Parse(time.DateTime, value)
+		inputTh.ListId = &listId
+	}
+	if form.Has("checklist_auto_complete") {
+		if inputTh.ChecklistAutoComplete, err = strconv.ParseBool(form.Get("checklist_auto_complete")); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid checklist_auto_complete")
+			return
 		}
 	}
 
-	slog.Info("task", input)
-	id, err := h.services.TaskManagerTask.Create(inputTh)
+	id, err := h.services.TaskManagerTask.Create(c.Request.Context(), inputTh)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
-	slog.Info("create task success",
-		"task", input,
-		"task", id,
-	)
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"id": id,
 	})
@@ -75,32 +90,51 @@ type getAllTasksResponse struct {
 
 // @Summary Get All Tasks
 // @Tags tasks
-// @Description get all tasks
+// @Description get all tasks, overdue first and then by priority and due time unless sort is given
 // @ID get-all-tasks
 // @Accept  json
 // @Produce  json
 // @Param id path int true "telegram ID"
+// @Param sort query string false "sort order" Enums(priority, due_at, start_time, created_at)
+// @Param tag query []string false "keep tasks with these tags" collectionFormat(multi)
+// @Param tag_mode query string false "whether tasks need all or any of the tags, all by default" Enums(and, or)
+// @Param list_id query int false "keep tasks of the list, ordered by position unless sort is given"
+// @Param archived query bool false "include tasks of archived lists"
+// @Param If-None-Match header string false "ETag of a previously fetched list"
 // @Success 200 {object} getAllTasksResponse
+// @Success 304
 // @Failure 400,404 {object} errorResponse
 // @Failure 500 {object} errorResponse
 // @Failure default {object} errorResponse
 // @Router /api/telegram/{id} [get]
 func (h *Handler) getTasksByTelegramId(c *gin.Context) {
-	slog.Info("start get all tasks")
-
 	telegramId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
 
-	tasks, err := h.services.TaskManagerTask.GetAll(telegramId)
+	filter := task_manager.TaskFilter{
+		Sort:     task_manager.TaskSort(c.Query("sort")),
+		Tags:     c.QueryArray("tag"),
+		TagMode:  task_manager.TagMode(c.Query("tag_mode")),
+		Archived: c.Query("archived") == "true",
+	}
+	if listId := c.Query("list_id"); listId != "" {
+		if filter.ListId, err = strconv.Atoi(listId); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid list_id")
+			return
+		}
+	}
+	tasks, err := h.services.TaskManagerTask.GetAll(c.Request.Context(), telegramId, filter)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
+		return
+	}
+	if notModified(c, tasksETag(tasks)) {
 		return
 	}
 
-	slog.Info("get all tasks success")
 	c.JSON(http.StatusOK, getAllTasksResponse{
 		Data: tasks,
 	})
@@ -113,28 +147,165 @@ func (h *Handler) getTasksByTelegramId(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path int true "Task ID"
+// @Param If-None-Match header string false "ETag of a previously fetched task"
 // @Success 200 {object} task_manager.Task
+// @Success 304
 // @Failure 400,404 {object} errorResponse
 // @Failure 500 {object} errorResponse
 // @Failure default {object} errorResponse
 // @Router /api/tasks/{id} [get]
 func (h *Handler) getTaskById(c *gin.Context) {
-	slog.Info("start get task by id")
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	task, err := h.services.TaskManagerTask.GetById(c.Request.Context(), taskId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+	if notModified(c, taskETag(task)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Update task
+// @Tags tasks
+// @Description update text, start time, priority or due time of a task
+// @ID update-task
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag of the task"
+// @Param input body task_manager.UpdateTaskInput true "fields to update"
+// @Success 200 {object} task_manager.Task
+// @Failure 400,404 {object} errorResponse
+// @Failure 412,428 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id} [put]
+func (h *Handler) updateTask(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	version, ok := requireVersion(c)
+	if !ok {
+		return
+	}
+
+	var input task_manager.UpdateTaskInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+	if input.StartTimeStr != nil {
+		startTime, err := parseTime(*input.StartTimeStr)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid start_time")
+			return
+		}
+		input.StartTime = &startTime
+	}
+	if input.DueAtStr != nil {
+		dueAt, err := parseTime(*input.DueAtStr)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "invalid due_at")
+			return
+		}
+		input.DueAt = &dueAt
+	}
+
+	task, err := h.services.TaskManagerTask.Update(c.Request.Context(), taskId, input, version)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.Header(etagHeader, taskETag(task))
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Complete task
+// @Tags tasks
+// @Description mark task as done
+// @ID complete-task
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag of the task"
+// @Success 200 {object} task_manager.Task
+// @Failure 400,404 {object} errorResponse
+// @Failure 412,428 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/complete [post]
+func (h *Handler) completeTask(c *gin.Context) {
+	taskId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	version, ok := requireVersion(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.services.TaskManagerTask.Complete(c.Request.Context(), taskId, version)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
 
+	c.Header(etagHeader, taskETag(task))
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Move task
+// @Tags tasks
+// @Description move task to another list, or out of lists when list_id is null
+// @ID move-task
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag of the task"
+// @Param input body task_manager.MoveTaskInput true "target list and position"
+// @Success 200 {object} task_manager.Task
+// @Failure 400,404 {object} errorResponse
+// @Failure 409 {object} errorResponse "list_archived"
+// @Failure 412,428 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/tasks/{id}/move [post]
+func (h *Handler) moveTask(c *gin.Context) {
 	taskId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
+	version, ok := requireVersion(c)
+	if !ok {
+		return
+	}
 
-	task, err := h.services.TaskManagerTask.GetById(taskId)
+	var input task_manager.MoveTaskInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	task, err := h.services.TaskManagerTask.Move(c.Request.Context(), taskId, input, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
-	slog.Info("get task by id success",
-		"task", taskId)
+	c.Header(etagHeader, taskETag(task))
 	c.JSON(http.StatusOK, task)
 }
 
@@ -145,29 +316,43 @@ func (h *Handler) getTaskById(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag of the task"
 // @Success 200 {string} ok
 // @Failure 400,404 {object} errorResponse
+// @Failure 412,428 {object} errorResponse
 // @Failure 500 {object} errorResponse
 // @Failure default {object} errorResponse
 // @Router /api/tasks/{id} [delete]
 func (h *Handler) deleteTask(c *gin.Context) {
-	slog.Info("start delete tasks")
-
 	taskId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
 
-	err = h.services.TaskManagerTask.Delete(taskId)
+	version, ok := requireVersion(c)
+	if !ok {
+		return
+	}
+
+	err = h.services.TaskManagerTask.Delete(c.Request.Context(), taskId, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
-	slog.Info("delete task success",
-		"task_id", taskId)
 	c.JSON(http.StatusOK, statusResponse{
 		Status: "ok",
 	})
 }
+
+// parseTime accepts the "2006-01-02 15:04:05" format the bot sends, taken
+// in UTC, and RFC 3339. Task times are stored without a zone, so the time
+// is returned in UTC.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateTime, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2026-01-01 10:00:00", want: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2026-01-01T10:00:00Z", want: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2026-01-01T10:00:00+03:00", want: time.Date(2026, 1, 1, 7, 0, 0, 0, time.UTC)},
+		{value: "2026-01-01T01:30:00-05:30", want: time.Date(2026, 1, 1, 7, 0, 0, 0, time.UTC)},
+		{value: "2026-01-01", wantErr: true},
+		{value: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTime(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTime(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getAllTemplatesResponse struct {
+	Data []task_manager.TaskTemplate `json:"data"`
+}
+
+type instantiateTemplateResponse struct {
+	Data []task_manager.Task `json:"data"`
+}
+
+// @Summary Get templates
+// @Tags templates
+// @Description get task templates of a user
+// @ID get-templates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} getAllTemplatesResponse
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/templates [get]
+func (h *Handler) getTemplates(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	templates, err := h.services.Template.GetAll(c.Request.Context(), telegramId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllTemplatesResponse{
+		Data: templates,
+	})
+}
+
+// @Summary Create template
+// @Tags templates
+// @Description create a task template; text may contain {date}, {time}, {weekday} and {n}
+// @ID create-template
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param input body task_manager.TaskTemplateInput true "template info"
+// @Success 200 {object} task_manager.TaskTemplate
+// @Failure 400,409 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/templates [post]
+func (h *Handler) createTemplate(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.TaskTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	template, err := h.services.Template.Create(c.Request.Context(), telegramId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Get template By Id
+// @Tags templates
+// @Description get task template by id
+// @ID get-template-by-id
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Template ID"
+// @Success 200 {object} task_manager.TaskTemplate
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/templates/{id} [get]
+func (h *Handler) getTemplateById(c *gin.Context) {
+	templateId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	template, err := h.services.Template.GetById(c.Request.Context(), templateId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Update template
+// @Tags templates
+// @Description replace a task template
+// @ID update-template
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Template ID"
+// @Param input body task_manager.TaskTemplateInput true "template info"
+// @Success 200 {object} task_manager.TaskTemplate
+// @Failure 400,404,409 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/templates/{id} [put]
+func (h *Handler) updateTemplate(c *gin.Context) {
+	templateId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.TaskTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	template, err := h.services.Template.Update(c.Request.Context(), templateId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Delete template
+// @Tags templates
+// @Description delete task template; tasks created from it are kept
+// @ID delete-template
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Template ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/templates/{id} [delete]
+func (h *Handler) deleteTemplate(c *gin.Context) {
+	templateId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Template.Delete(c.Request.Context(), templateId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+// @Summary Instantiate template
+// @Tags templates
+// @Description create tasks from a template for every occurrence of its recurrence starting at base_time
+// @ID instantiate-template
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Template ID"
+// @Param input body task_manager.InstantiateTemplateInput true "base time"
+// @Success 200 {object} instantiateTemplateResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 403 {object} errorResponse "quota_exceeded"
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/templates/{id}/instantiate [post]
+func (h *Handler) instantiateTemplate(c *gin.Context) {
+	templateId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.InstantiateTemplateInput
+	if err := c.ShouldBind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+	if input.BaseTime, err = parseTime(input.BaseTimeStr); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid base_time")
+		return
+	}
+
+	tasks, err := h.services.Template.Instantiate(c.Request.Context(), templateId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instantiateTemplateResponse{
+		Data: tasks,
+	})
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"task_manager"
+)
+
+type getAllWebhooksResponse struct {
+	Data []task_manager.Webhook `json:"data"`
+}
+
+type getAllDeliveriesResponse struct {
+	Data []task_manager.WebhookDelivery `json:"data"`
+}
+
+// @Summary Get webhooks
+// @Tags webhooks
+// @Description get webhooks of a user; secrets are not returned
+// @ID get-webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Success 200 {object} getAllWebhooksResponse
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/webhooks [get]
+func (h *Handler) getWebhooks(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	webhooks, err := h.services.Webhook.GetAll(c.Request.Context(), telegramId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllWebhooksResponse{
+		Data: webhooks,
+	})
+}
+
+// @Summary Create webhook
+// @Tags webhooks
+// @Description subscribe a URL to events of a user; the secret signing the payloads is generated unless given and returned only here
+// @ID create-webhook
+// @Accept  json
+// @Produce  json
+// @Param id path int true "telegram ID"
+// @Param input body task_manager.WebhookInput true "webhook info"
+// @Success 200 {object} task_manager.Webhook
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/telegram/{id}/webhooks [post]
+func (h *Handler) createWebhook(c *gin.Context) {
+	telegramId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	webhook, err := h.services.Webhook.Create(c.Request.Context(), telegramId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary Get webhook By Id
+// @Tags webhooks
+// @Description get webhook by id
+// @ID get-webhook-by-id
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} task_manager.Webhook
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhooks/{id} [get]
+func (h *Handler) getWebhookById(c *gin.Context) {
+	webhookId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	webhook, err := h.services.Webhook.GetById(c.Request.Context(), webhookId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary Update webhook
+// @Tags webhooks
+// @Description replace URL and event types of a webhook; an empty secret keeps the current one
+// @ID update-webhook
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Webhook ID"
+// @Param input body task_manager.WebhookInput true "webhook info"
+// @Success 200 {object} task_manager.Webhook
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhooks/{id} [put]
+func (h *Handler) updateWebhook(c *gin.Context) {
+	webhookId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	var input task_manager.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	webhook, err := h.services.Webhook.Update(c.Request.Context(), webhookId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary Delete webhook
+// @Tags webhooks
+// @Description delete webhook with its delivery log
+// @ID delete-webhook
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} statusResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhooks/{id} [delete]
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	webhookId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Webhook.Delete(c.Request.Context(), webhookId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+// @Summary Get webhook deliveries
+// @Tags webhooks
+// @Description get the latest deliveries of a webhook, newest first
+// @ID get-webhook-deliveries
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "number of deliveries, 50 by default and at most 500"
+// @Success 200 {object} getAllDeliveriesResponse
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhooks/{id}/deliveries [get]
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	webhookId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		newErrorResponse(c, http.StatusBadRequest, "invalid limit param")
+		return
+	}
+
+	deliveries, err := h.services.Webhook.GetDeliveries(c.Request.Context(), webhookId, limit)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllDeliveriesResponse{
+		Data: deliveries,
+	})
+}
+
+// @Summary Test webhook
+// @Tags webhooks
+// @Description send a webhook.test event right away; the delivery is returned whether the receiver accepted it or not
+// @ID test-webhook
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} task_manager.WebhookDelivery
+// @Failure 400,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhooks/{id}/test [post]
+func (h *Handler) testWebhook(c *gin.Context) {
+	webhookId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	delivery, err := h.services.Webhook.SendTest(c.Request.Context(), webhookId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
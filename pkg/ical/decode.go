@@ -0,0 +1,205 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const dateFormat = "20060102"
+
+var ErrMalformed = errors.New("malformed iCalendar data")
+
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Text returns the unescaped TEXT value.
+func (p Property) Text() string {
+	var b strings.Builder
+	for i := 0; i < len(p.Value); i++ {
+		if p.Value[i] != '\\' || i+1 == len(p.Value) {
+			b.WriteByte(p.Value[i])
+			continue
+		}
+		i++
+		switch p.Value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(p.Value[i])
+		}
+	}
+	return b.String()
+}
+
+// Texts returns the unescaped values of a comma separated TEXT list.
+func (p Property) Texts() []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(p.Value); i++ {
+		switch p.Value[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, Property{Value: p.Value[start:i]}.Text())
+			start = i + 1
+		}
+	}
+	return append(values, Property{Value: p.Value[start:]}.Text())
+}
+
+// Time parses a DATE or DATE-TIME value. Times in UTC keep their zone,
+// times with a TZID parameter are resolved with zones and floating times
+// and dates are taken in loc.
+func (p Property) Time(loc *time.Location, zones *TimeZones) (time.Time, error) {
+	if p.Params["VALUE"] == "DATE" || len(p.Value) == len(dateFormat) {
+		return time.ParseInLocation(dateFormat, p.Value, loc)
+	}
+	if strings.HasSuffix(p.Value, "Z") {
+		return time.Parse(timeFormat, p.Value)
+	}
+	if tzid, ok := p.Params["TZID"]; ok {
+		wall, err := time.Parse(strings.TrimSuffix(timeFormat, "Z"), p.Value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return zones.In(tzid, wall)
+	}
+	return time.ParseInLocation(strings.TrimSuffix(timeFormat, "Z"), p.Value, loc)
+}
+
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// Get returns the first property called name.
+func (c *Component) Get(name string) (Property, bool) {
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// Parse reads the first component of r, usually a VCALENDAR, with its
+// subcomponents.
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	for _, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch prop.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("%w: unexpected END:%s", ErrMalformed, prop.Value)
+			}
+			if len(stack) == 1 {
+				return stack[0], nil
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: property %s outside of a component", ErrMalformed, prop.Name)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+	return nil, fmt.Errorf("%w: unterminated component", ErrMalformed)
+}
+
+// unfold joins folded content lines.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into name, parameters and value.
+func parseLine(line string) (prop Property, err error) {
+	quoted := false
+	nameEnd, valueStart := -1, -1
+	for i := 0; i < len(line) && valueStart < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if nameEnd < 0 {
+				nameEnd = i
+			}
+		case ':':
+			if !quoted {
+				valueStart = i + 1
+			}
+		}
+	}
+	if valueStart < 0 {
+		return prop, fmt.Errorf("%w: line without value", ErrMalformed)
+	}
+	if nameEnd < 0 {
+		nameEnd = valueStart - 1
+	}
+
+	prop.Name = strings.ToUpper(line[:nameEnd])
+	prop.Value = line[valueStart:]
+	prop.Params = make(map[string]string)
+	if nameEnd < valueStart-1 {
+		for _, param := range splitParams(line[nameEnd+1 : valueStart-1]) {
+			name, value, _ := strings.Cut(param, "=")
+			prop.Params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop, nil
+}
+
+func splitParams(params string) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(params); i++ {
+		switch params[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, params[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, params[start:])
+}
@@ -0,0 +1,136 @@
+package ical
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	const data = "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1@example.com\r\n" +
+		"SUMMARY:Buy milk\\, bread\\; and \r\n" +
+		" eggs\\nat the store\r\n" +
+		"DESCRIPTION:tab\r\n" +
+		"\tfolded\r\n" +
+		"DTSTART;TZID=\"Europe/Berlin\";VALUE=DATE-TIME:20260302T083000\r\n" +
+		"CATEGORIES:work,home\\,garden\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	calendar, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calendar.Name != "VCALENDAR" || len(calendar.Components) != 1 {
+		t.Fatalf("calendar = %s with %d components", calendar.Name, len(calendar.Components))
+	}
+	event := calendar.Components[0]
+	if len(event.Components) != 1 || event.Components[0].Name != "VALARM" {
+		t.Errorf("event components = %v", event.Components)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "UID", want: "1@example.com"},
+		{name: "SUMMARY", want: "Buy milk, bread; and eggs\nat the store"},
+		{name: "DESCRIPTION", want: "tabfolded"},
+		{name: "DTSTART", want: "20260302T083000"},
+	}
+	for _, tt := range tests {
+		prop, ok := event.Get(tt.name)
+		if !ok {
+			t.Errorf("%s is missing", tt.name)
+			continue
+		}
+		if got := prop.Text(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	start, _ := event.Get("DTSTART")
+	if start.Params["TZID"] != "Europe/Berlin" || start.Params["VALUE"] != "DATE-TIME" {
+		t.Errorf("DTSTART params = %v", start.Params)
+	}
+	categories, _ := event.Get("CATEGORIES")
+	if got := categories.Texts(); len(got) != 2 || got[0] != "work" || got[1] != "home,garden" {
+		t.Errorf("CATEGORIES = %q", got)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for name, data := range map[string]string{
+		"unterminated":        "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"mismatched END":      "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VTODO\r\n",
+		"property outside":    "VERSION:2.0\r\nBEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"line without value":  "BEGIN:VCALENDAR\r\nVERSION\r\nEND:VCALENDAR\r\n",
+		"quoted colon only":   "BEGIN:VCALENDAR\r\nX;P=\"a:b\"\r\nEND:VCALENDAR\r\n",
+		"empty":               "",
+		"END without a BEGIN": "END:VCALENDAR\r\n",
+	} {
+		if _, err := Parse(strings.NewReader(data)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: err = %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestEncoderFoldsForParse(t *testing.T) {
+	summary := strings.Repeat("задача, ", 30) + "done"
+	var b strings.Builder
+	enc := NewEncoder(&b)
+	enc.Begin("VCALENDAR")
+	enc.Text("SUMMARY", summary)
+	enc.End("VCALENDAR")
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	calendar, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop, _ := calendar.Get("SUMMARY")
+	if got := prop.Text(); got != summary {
+		t.Errorf("SUMMARY = %q, want %q", got, summary)
+	}
+}
+
+func TestPropertyTime(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*3600)
+	tests := []struct {
+		prop Property
+		want time.Time
+	}{
+		{prop: Property{Value: "20260302T083000Z"}, want: time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC)},
+		{prop: Property{Value: "20260302T083000"}, want: time.Date(2026, 3, 2, 5, 30, 0, 0, time.UTC)},
+		{prop: Property{Value: "20260302"}, want: time.Date(2026, 3, 1, 21, 0, 0, 0, time.UTC)},
+		{prop: Property{Value: "20260302", Params: map[string]string{"VALUE": "DATE"}}, want: time.Date(2026, 3, 1, 21, 0, 0, 0, time.UTC)},
+		{prop: Property{Value: "20260302T083000", Params: map[string]string{"TZID": "Etc/GMT-2"}}, want: time.Date(2026, 3, 2, 6, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := tt.prop.Time(moscow, nil)
+		if err != nil {
+			t.Errorf("Time(%s %v): %v", tt.prop.Value, tt.prop.Params, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Time(%s %v) = %s, want %s", tt.prop.Value, tt.prop.Params, got.UTC(), tt.want)
+		}
+	}
+	if _, err := (Property{Value: "20260302T083000", Params: map[string]string{"TZID": "Nowhere"}}).Time(moscow, nil); err == nil {
+		t.Errorf("Time with an unknown TZID did not fail")
+	}
+}
@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	// maxLineOctets is the line length limit without the line break.
+	maxLineOctets = 75
+	timeFormat    = "20060102T150405Z"
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// Encoder writes content lines folded to 75 octets and terminated by CRLF.
+// The first write error is kept and returned by Flush.
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+func (e *Encoder) Begin(component string) {
+	e.Property("BEGIN", component)
+}
+
+func (e *Encoder) End(component string) {
+	e.Property("END", component)
+}
+
+// Property writes a property with a value that is already encoded, like
+// "DISPLAY" or "-PT15M". Name may carry parameters: "TRIGGER;VALUE=DATE-TIME".
+func (e *Encoder) Property(name, value string) {
+	e.writeLine(name + ":" + value)
+}
+
+// Text writes a TEXT value, escaping backslashes, separators and newlines.
+func (e *Encoder) Text(name, value string) {
+	e.Property(name, EscapeText(value))
+}
+
+// Time writes a DATE-TIME value in UTC.
+func (e *Encoder) Time(name string, t time.Time) {
+	e.Property(name, FormatTime(t))
+}
+
+// Flush writes buffered lines and returns the first error.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.w.Flush()
+	return e.err
+}
+
+func (e *Encoder) writeLine(line string) {
+	if e.err != nil {
+		return
+	}
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		e.write(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space.
+		limit = maxLineOctets - 1
+	}
+	e.write(line + "\r\n")
+}
+
+func (e *Encoder) write(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func EscapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
@@ -0,0 +1,233 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// TimeZones resolves TZID parameters. IANA names are looked up in the time
+// zone database, other names in the VTIMEZONE components of the calendar
+// and then among the Windows names written by Outlook and Exchange.
+type TimeZones struct {
+	defined map[string]*timeZone
+}
+
+// NewTimeZones collects the VTIMEZONE components of calendar.
+func NewTimeZones(calendar *Component) *TimeZones {
+	zones := &TimeZones{defined: make(map[string]*timeZone)}
+	for _, component := range calendar.Components {
+		if component.Name != "VTIMEZONE" {
+			continue
+		}
+		tzid, ok := component.Get("TZID")
+		if !ok {
+			continue
+		}
+		if zone := parseTimeZone(component); len(zone.observances) > 0 {
+			zones.defined[tzid.Value] = zone
+		}
+	}
+	return zones
+}
+
+// In returns the time with the wall clock of wall, whose zone is ignored,
+// in the zone called tzid.
+func (z *TimeZones) In(tzid string, wall time.Time) (time.Time, error) {
+	date := func(loc *time.Location) time.Time {
+		return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	}
+	name := strings.TrimPrefix(tzid, "/")
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return date(loc), nil
+		}
+	}
+	if z != nil {
+		if zone, ok := z.defined[tzid]; ok {
+			return date(time.FixedZone(tzid, zone.offset(wall))), nil
+		}
+	}
+	if iana, ok := windowsZones[name]; ok {
+		if loc, err := time.LoadLocation(iana); err == nil {
+			return date(loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+}
+
+type timeZone struct {
+	observances []observance
+}
+
+// observance is a STANDARD or DAYLIGHT component. Times are wall clocks
+// kept in UTC.
+type observance struct {
+	start    time.Time
+	from, to int
+	// month is zero unless the observance recurs yearly on the week-th
+	// weekday of month, counting from the end when week is negative.
+	month   time.Month
+	week    int
+	weekday time.Weekday
+}
+
+func parseTimeZone(component *Component) *timeZone {
+	zone := &timeZone{}
+	for _, sub := range component.Components {
+		if sub.Name != "STANDARD" && sub.Name != "DAYLIGHT" {
+			continue
+		}
+		var (
+			o   observance
+			err error
+		)
+		dtstart, ok := sub.Get("DTSTART")
+		if !ok {
+			continue
+		}
+		if o.start, err = time.Parse(strings.TrimSuffix(timeFormat, "Z"), strings.TrimSuffix(dtstart.Value, "Z")); err != nil {
+			continue
+		}
+		from, _ := sub.Get("TZOFFSETFROM")
+		to, _ := sub.Get("TZOFFSETTO")
+		if o.to, err = parseOffset(to.Value); err != nil {
+			continue
+		}
+		if o.from, err = parseOffset(from.Value); err != nil {
+			o.from = o.to
+		}
+		if rrule, ok := sub.Get("RRULE"); ok {
+			o.parseRule(rrule.Value)
+		}
+		zone.observances = append(zone.observances, o)
+	}
+	return zone
+}
+
+// parseOffset parses a UTC offset like -0500 or +053000 into seconds.
+func parseOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || value[0] != '+' && value[0] != '-' {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	var seconds int
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// parseRule reads the yearly rules time zones are written with, such as
+// FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU. Other rules leave the observance
+// without a recurrence.
+func (o *observance) parseRule(rule string) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			parts[key] = value
+		}
+	}
+	month, err := strconv.Atoi(parts["BYMONTH"])
+	if parts["FREQ"] != "YEARLY" || err != nil || month < 1 || month > 12 {
+		return
+	}
+	byday := parts["BYDAY"]
+	if len(byday) < 2 {
+		return
+	}
+	weekday, ok := weekdays[byday[len(byday)-2:]]
+	if !ok {
+		return
+	}
+	week := 1
+	switch {
+	case len(byday) > 2:
+		if week, err = strconv.Atoi(byday[:len(byday)-2]); err != nil {
+			return
+		}
+	case parts["BYSETPOS"] != "":
+		if week, err = strconv.Atoi(parts["BYSETPOS"]); err != nil {
+			return
+		}
+	case parts["BYMONTHDAY"] != "":
+		day, _, _ := strings.Cut(parts["BYMONTHDAY"], ",")
+		if n, err := strconv.Atoi(day); err == nil && n > 0 {
+			week = (n-1)/7 + 1
+		} else if err == nil && n < 0 {
+			week = -1
+		}
+	}
+	if week == 0 || week > 5 || week < -5 {
+		return
+	}
+	o.month, o.week, o.weekday = time.Month(month), week, weekday
+}
+
+// onset returns when the observance begins in year.
+func (o observance) onset(year int) time.Time {
+	hour, min, sec := o.start.Clock()
+	if o.week > 0 {
+		first := time.Date(year, o.month, 1, hour, min, sec, 0, time.UTC)
+		day := first.AddDate(0, 0, (int(o.weekday)-int(first.Weekday())+7)%7+7*(o.week-1))
+		for day.Month() != o.month {
+			day = day.AddDate(0, 0, -7)
+		}
+		return day
+	}
+	last := time.Date(year, o.month+1, 0, hour, min, sec, 0, time.UTC)
+	day := last.AddDate(0, 0, -(int(last.Weekday())-int(o.weekday)+7)%7+7*(o.week+1))
+	for day.Month() != o.month {
+		day = day.AddDate(0, 0, 7)
+	}
+	return day
+}
+
+// lastOnset returns the latest beginning of the observance not after wall.
+func (o observance) lastOnset(wall time.Time) (time.Time, bool) {
+	if o.month != 0 {
+		for year := wall.Year(); year >= wall.Year()-1; year-- {
+			if onset := o.onset(year); !onset.After(wall) && !onset.Before(o.start) {
+				return onset, true
+			}
+		}
+	}
+	return o.start, !o.start.After(wall)
+}
+
+// offset returns the UTC offset in seconds of the observance in effect at
+// wall. Before the first observance its TZOFFSETFROM applies.
+func (z *timeZone) offset(wall time.Time) int {
+	var (
+		current, first *observance
+		onset          time.Time
+	)
+	for i := range z.observances {
+		o := &z.observances[i]
+		if first == nil || o.start.Before(first.start) {
+			first = o
+		}
+		if at, ok := o.lastOnset(wall); ok && (current == nil || at.After(onset)) {
+			current, onset = o, at
+		}
+	}
+	if current == nil {
+		return first.from
+	}
+	return current.to
+}
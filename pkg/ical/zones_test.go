@@ -0,0 +1,189 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const zonesCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Custom Central Europe\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16011028T030000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:16010325T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:+0100\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Custom Eastern\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:20071104T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\n" +
+	"TZOFFSETFROM:-0400\r\n" +
+	"TZOFFSETTO:-0500\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:20070311T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8,9,10,11,12,13,14;BYDAY=SU\r\n" +
+	"TZOFFSETFROM:-0500\r\n" +
+	"TZOFFSETTO:-0400\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Custom Fixed\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:20200101T000000\r\n" +
+	"TZOFFSETFROM:+0530\r\n" +
+	"TZOFFSETTO:+0545\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestTimeZonesIn(t *testing.T) {
+	calendar, err := Parse(strings.NewReader(zonesCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := NewTimeZones(calendar)
+	wall := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		tzid string
+		wall time.Time
+		// offset in hours
+		want float64
+	}{
+		// In 2026 the last Sundays of March and October are the 29th and 25th.
+		{tzid: "Custom Central Europe", wall: wall(1, 15, 12), want: 1},
+		{tzid: "Custom Central Europe", wall: wall(3, 29, 1), want: 1},
+		{tzid: "Custom Central Europe", wall: wall(3, 29, 3), want: 2},
+		{tzid: "Custom Central Europe", wall: wall(7, 1, 12), want: 2},
+		{tzid: "Custom Central Europe", wall: wall(10, 25, 2), want: 2},
+		{tzid: "Custom Central Europe", wall: wall(10, 25, 4), want: 1},
+		{tzid: "Custom Central Europe", wall: wall(12, 31, 23), want: 1},
+		// The second Sunday of March 2026 is the 8th, the first of November the 1st.
+		{tzid: "Custom Eastern", wall: wall(3, 8, 1), want: -5},
+		{tzid: "Custom Eastern", wall: wall(3, 8, 3), want: -4},
+		{tzid: "Custom Eastern", wall: wall(11, 1, 1), want: -4},
+		{tzid: "Custom Eastern", wall: wall(11, 1, 3), want: -5},
+		// Before the only observance its TZOFFSETFROM applies.
+		{tzid: "Custom Fixed", wall: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), want: 5.5},
+		{tzid: "Custom Fixed", wall: wall(6, 1, 0), want: 5.75},
+	}
+	for _, tt := range tests {
+		got, err := zones.In(tt.tzid, tt.wall)
+		if err != nil {
+			t.Errorf("In(%q, %s): %v", tt.tzid, tt.wall, err)
+			continue
+		}
+		want := tt.wall.Add(-time.Duration(tt.want * float64(time.Hour)))
+		if !got.Equal(want) {
+			t.Errorf("In(%q, %s) = %s, want %s", tt.tzid, tt.wall.Format(time.DateTime), got.UTC(), want)
+		}
+	}
+}
+
+func TestTimeZonesInNames(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("time zone database is not available")
+	}
+	wall := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	for _, tzid := range []string{"Europe/Berlin", "/Europe/Berlin", "W. Europe Standard Time"} {
+		got, err := (*TimeZones)(nil).In(tzid, wall)
+		if err != nil {
+			t.Errorf("In(%q): %v", tzid, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("In(%q) = %s, want %s", tzid, got.UTC(), want)
+		}
+	}
+	if _, err := (*TimeZones)(nil).In("Nowhere Standard Time", wall); err == nil {
+		t.Errorf("In with an unknown zone did not fail")
+	}
+}
+
+func TestObservanceOnset(t *testing.T) {
+	start := time.Date(1970, 1, 1, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule string
+		year int
+		want time.Time
+	}{
+		{rule: "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU", year: 2026, want: time.Date(2026, 3, 29, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU", year: 2026, want: time.Date(2026, 10, 25, 2, 0, 0, 0, time.UTC)},
+		// The last day of May 2026 is a Sunday.
+		{rule: "FREQ=YEARLY;BYMONTH=5;BYDAY=-1SU", year: 2026, want: time.Date(2026, 5, 31, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=5;BYDAY=-2SU", year: 2026, want: time.Date(2026, 5, 24, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", year: 2026, want: time.Date(2026, 3, 8, 2, 0, 0, 0, time.UTC)},
+		// March 1, 2026 is a Sunday.
+		{rule: "FREQ=YEARLY;BYMONTH=3;BYDAY=1SU", year: 2026, want: time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=1SU", year: 2026, want: time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC)},
+		// A fifth Sunday that does not exist falls back to the last one.
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYDAY=5SU", year: 2026, want: time.Date(2026, 2, 22, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=4;BYDAY=FR;BYSETPOS=-1", year: 2026, want: time.Date(2026, 4, 24, 2, 0, 0, 0, time.UTC)},
+		{rule: "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8,9,10,11,12,13,14;BYDAY=SU", year: 2027, want: time.Date(2027, 3, 14, 2, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		o := observance{start: start}
+		o.parseRule(tt.rule)
+		if o.month == 0 {
+			t.Errorf("parseRule(%q) found no recurrence", tt.rule)
+			continue
+		}
+		if got := o.onset(tt.year); !got.Equal(tt.want) {
+			t.Errorf("%s in %d: onset = %s, want %s", tt.rule, tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestObservanceParseRuleUnsupported(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=MONTHLY;BYMONTH=3;BYDAY=-1SU",
+		"FREQ=YEARLY;BYDAY=-1SU",
+		"FREQ=YEARLY;BYMONTH=13;BYDAY=-1SU",
+		"FREQ=YEARLY;BYMONTH=3;BYDAY=XX",
+		"FREQ=YEARLY;BYMONTH=3;BYDAY=6SU",
+		"FREQ=YEARLY;BYMONTH=3;BYDAY=0SU",
+	} {
+		var o observance
+		if o.parseRule(rule); o.month != 0 {
+			t.Errorf("parseRule(%q) = %+v, want no recurrence", rule, o)
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "+0100", want: 3600},
+		{value: "-0500", want: -5 * 3600},
+		{value: "+0530", want: 5*3600 + 30*60},
+		{value: "+053015", want: 5*3600 + 30*60 + 15},
+		{value: "-0000", want: 0},
+		{value: "0100", wantErr: true},
+		{value: "+1", wantErr: true},
+		{value: "+01:00", wantErr: true},
+		{value: "+0x00", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOffset(%q) = %d, %v, want %d, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+const collectTimeout = 5 * time.Second
+
+type TaskCounter interface {
+	CountActive(ctx context.Context) (pending int, overdue int, err error)
+}
+
+type tasksCollector struct {
+	counter TaskCounter
+	pending *prometheus.Desc
+	overdue *prometheus.Desc
+}
+
+func newTasksCollector(counter TaskCounter) *tasksCollector {
+	return &tasksCollector{
+		counter: counter,
+		pending: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tasks", "pending"),
+			"Number of unfinished tasks whose reminder time has not come yet.",
+			nil, nil,
+		),
+		overdue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tasks", "overdue"),
+			"Number of unfinished tasks whose reminder time has already passed.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *tasksCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.overdue
+}
+
+func (c *tasksCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	pending, overdue, err := c.counter.CountActive(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.pending, err)
+		ch <- prometheus.NewInvalidMetric(c.overdue, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(pending))
+	ch <- prometheus.MustNewConstMetric(c.overdue, prometheus.GaugeValue, float64(overdue))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 2}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		limit Limit
+		want  Result
+	}{
+		{name: "full bucket", limit: limit,
+			want: Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Second}},
+		{name: "last token", limit: limit,
+			want: Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second}},
+		{name: "empty bucket", limit: limit,
+			want: Result{Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second, RetryAfter: time.Second}},
+		{name: "partly refilled", after: 500 * time.Millisecond, limit: limit,
+			want: Result{Limit: 2, Remaining: 0, ResetAfter: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{name: "refilled token", after: 500 * time.Millisecond, limit: limit,
+			want: Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second}},
+		{name: "refill stops at burst", after: time.Hour, limit: limit,
+			want: Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Second}},
+		{name: "changed limit starts a new bucket", limit: Limit{Rate: 1, Burst: 5},
+			want: Result{Allowed: true, Limit: 5, Remaining: 4, ResetAfter: time.Second}},
+		{name: "disabled limit", limit: Limit{},
+			want: Result{Allowed: true}},
+	}
+
+	now := start
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	for _, tt := range tests {
+		now = now.Add(tt.after)
+		got, err := store.Take(context.Background(), "key", tt.limit)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Take = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	for _, key := range []string{"a", "b"} {
+		if result, _ := store.Take(context.Background(), key, limit); !result.Allowed {
+			t.Errorf("first Take of %s was not allowed", key)
+		}
+	}
+	if result, _ := store.Take(context.Background(), "a", limit); result.Allowed {
+		t.Errorf("second Take of a was allowed")
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	_, _ = store.Take(context.Background(), "key", Limit{Rate: 1, Burst: 2})
+
+	store.cleanup()
+	if len(store.buckets) != 1 {
+		t.Fatalf("cleanup dropped a bucket that is not full")
+	}
+	now = now.Add(time.Second)
+	store.cleanup()
+	if len(store.buckets) != 0 {
+		t.Errorf("cleanup kept a full bucket")
+	}
+}
+
+func TestResultSeconds(t *testing.T) {
+	result := Result{RetryAfter: 1100 * time.Millisecond, ResetAfter: 3 * time.Second}
+	if got := result.RetryAfterSeconds(); got != 2 {
+		t.Errorf("RetryAfterSeconds = %d, want 2", got)
+	}
+	if got := result.ResetAfterSeconds(); got != 3 {
+		t.Errorf("ResetAfterSeconds = %d, want 3", got)
+	}
+}
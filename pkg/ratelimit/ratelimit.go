@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit describes a token bucket refilled with Rate tokens per second and
+// holding at most Burst tokens. The zero Limit disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// ParseLimit parses limits written as "<count>/<s|m|h>[:<burst>]", e.g.
+// "10/m:5" allows ten requests per minute with bursts of five. Burst
+// defaults to count. An empty string or "0" disables limiting.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<unit>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad count", s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+
+	limit := Limit{Rate: float64(n) / per.Seconds(), Burst: n}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: bad burst", s)
+		}
+	}
+	return limit, nil
+}
+
+func (l *Limit) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	limit, err := ParseLimit(s)
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// Rule holds the limits applied to one route: one bucket per principal
+// (API token or client address) and one per telegram user.
+type Rule struct {
+	Principal Limit `json:"principal"`
+	Telegram  Limit `json:"telegram"`
+}
+
+// ParseRoutes parses per route rules from JSON keyed by "<METHOD> <route template>":
+//
+//	{"POST /api/tasks/": {"principal": "600/m", "telegram": "10/m:5"}}
+func ParseRoutes(s string) (map[string]Rule, error) {
+	routes := make(map[string]Rule)
+	if strings.TrimSpace(s) == "" {
+		return routes, nil
+	}
+	if err := json.Unmarshal([]byte(s), &routes); err != nil {
+		return nil, fmt.Errorf("invalid rate limit routes: %w", err)
+	}
+	return routes, nil
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next token, set when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from buckets identified by key. MemoryStore keeps the
+// buckets in process; a shared implementation lets several instances
+// enforce a common limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds as used by the
+// Retry-After header.
+func (r Result) RetryAfterSeconds() int {
+	return int(math.Ceil(r.RetryAfter.Seconds()))
+}
+
+func (r Result) ResetAfterSeconds() int {
+	return int(math.Ceil(r.ResetAfter.Seconds()))
+}
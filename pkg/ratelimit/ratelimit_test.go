@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"testing"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "", want: Limit{}},
+		{value: "0", want: Limit{}},
+		{value: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{value: "60/m:20", want: Limit{Rate: 1, Burst: 20}},
+		{value: " 3600/h ", want: Limit{Rate: 1, Burst: 3600}},
+		{value: "10", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "x/m", wantErr: true},
+		{value: "-1/m", wantErr: true},
+		{value: "10/m:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(`{"POST /api/tasks/": {"principal": "600/m:100", "telegram": "10/m:5"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := routes["POST /api/tasks/"]
+	if rule.Principal != (Limit{Rate: 10, Burst: 100}) || rule.Telegram.Burst != 5 {
+		t.Errorf("rule = %+v", rule)
+	}
+	if _, err := ParseRoutes(`{"GET /": {"principal": "1/y"}}`); err == nil {
+		t.Errorf("ParseRoutes accepted an invalid limit")
+	}
+}
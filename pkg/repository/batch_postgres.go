@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"task_manager"
+)
+
+// ErrBatchFailed rolls back a batch in atomic mode after an operation failed.
+var ErrBatchFailed = errors.New("batch operation failed")
+
+// Batch applies ops of the user and reports the result of every operation
+// in order. It must run in a transaction. In atomic mode the first failure
+// returns the results with ErrBatchFailed, so the transaction is rolled
+// back, and later operations are skipped; otherwise each operation runs in
+// a savepoint and a failure undoes only that operation.
+func (r *TaskPostgres) Batch(ctx context.Context, telegramId int, ops []task_manager.BatchOperation, atomic bool) (results []task_manager.BatchItemResult, err error) {
+	results = make([]task_manager.BatchItemResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		results[i] = task_manager.BatchItemResult{Index: i, Op: op.Op, TaskIds: []int{}}
+		if failed {
+			results[i].Status = task_manager.BatchSkipped
+			continue
+		}
+		if !atomic {
+			if _, err = r.db.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+				return nil, err
+			}
+		}
+
+		ids, opErr := r.batchOperation(ctx, telegramId, op)
+		if opErr == nil {
+			results[i].Status = task_manager.BatchOk
+			results[i].TaskIds = ids
+			continue
+		}
+		if isRetryable(opErr) {
+			return nil, opErr
+		}
+		results[i].Status = task_manager.BatchFailed
+		results[i].Error = opErr.Error()
+		if atomic {
+			failed = true
+			for j := 0; j < i; j++ {
+				results[j].Status = task_manager.BatchRolledBack
+			}
+			continue
+		}
+		if _, err = r.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_operation"); err != nil {
+			return nil, err
+		}
+	}
+	if failed {
+		return results, ErrBatchFailed
+	}
+	return results, nil
+}
+
+func (r *TaskPostgres) batchOperation(ctx context.Context, telegramId int, op task_manager.BatchOperation) (ids []int, err error) {
+	if op.Op == task_manager.BatchCreate {
+		return r.batchCreate(ctx, telegramId, op.Task)
+	}
+
+	where, args := batchWhere(telegramId, op)
+	var query, operation string
+	switch op.Op {
+	case task_manager.BatchUpdate:
+		task := op.Task
+		if task == nil {
+			task = &task_manager.BatchTask{}
+		}
+		n := len(args)
+		query = fmt.Sprintf(`UPDATE %s SET text = COALESCE($%d, text),
+			start_time_at = COALESCE($%d, start_time_at) + make_interval(secs => $%d),
+			priority = COALESCE($%d, priority),
+			due_at = CASE WHEN $%d THEN NULL ELSE COALESCE($%d, due_at) + make_interval(secs => $%d) END
+			WHERE %s RETURNING id`, tasksTable, n+1, n+2, n+5, n+3, n+6, n+4, n+5, where)
+		args = append(args, task.Text, task.StartTime, task.Priority, task.DueAt, op.Shift, task.ClearDueAt)
+		operation = "UPDATE"
+	case task_manager.BatchComplete:
+		if op.Filter != nil {
+			where += fmt.Sprintf(" AND status_end = '%s'", task_manager.Start)
+		}
+		query = fmt.Sprintf("UPDATE %s SET status_end = '%s' WHERE %s RETURNING id", tasksTable, task_manager.End, where)
+		operation = "UPDATE"
+	case task_manager.BatchDelete:
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s RETURNING id", tasksTable, where)
+		operation = "DELETE"
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	if ids, err = queryIds(ctx, r.db, tasksTable, operation, query, args...); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 && op.Filter == nil {
+		return nil, r.batchMissingOrConflict(ctx, telegramId, op.Id)
+	}
+	if op.Op == task_manager.BatchUpdate && op.Task != nil && op.Task.Text != nil {
+		for _, id := range ids {
+			if err = setTaskTags(ctx, r.db, telegramId, id, op.Task.Tags); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *TaskPostgres) batchCreate(ctx context.Context, telegramId int, task *task_manager.BatchTask) ([]int, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, list_id, text, status_end, start_time_at, priority, due_at, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, %s) RETURNING id`, tasksTable, nextPosition)
+	if err := lock(ctx, r.db, lockTaskPositions, strconv.Itoa(telegramId)); err != nil {
+		return nil, err
+	}
+	ids, err := queryIds(ctx, r.db, tasksTable, "INSERT", query, telegramId, task.ListId, task.Text, task_manager.Start,
+		task.StartTime, task.Priority, task.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = setTaskTags(ctx, r.db, telegramId, ids[0], task.Tags); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// batchWhere selects the task of the operation, if it is still at the
+// given version, or the unarchived tasks matching its filter.
+func batchWhere(telegramId int, op task_manager.BatchOperation) (string, []any) {
+	where, args := "telegram_id = $1", []any{telegramId}
+	if op.Filter == nil {
+		args = append(args, op.Id, op.Version)
+		return where + " AND id = $2 AND ($3 = 0 OR version = $3)", args
+	}
+
+	filter := op.Filter
+	where += " AND archived_at IS NULL"
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status_end = $%d", len(args))
+	}
+	if filter.ListId != 0 {
+		args = append(args, filter.ListId)
+		where += fmt.Sprintf(" AND list_id = $%d", len(args))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.StringArray(filter.Tags))
+		where += fmt.Sprintf(` AND (SELECT count(DISTINCT g.name) FROM %s tt JOIN %s g ON g.id = tt.tag_id
+			WHERE tt.task_id = tasks.id AND g.name = ANY($%[3]d::text[])) = cardinality($%[3]d::text[])`,
+			tasksTagsTable, tagsTable, len(args))
+	}
+	if filter.StartFrom != nil {
+		args = append(args, *filter.StartFrom)
+		where += fmt.Sprintf(" AND start_time_at >= $%d", len(args))
+	}
+	if filter.StartTo != nil {
+		args = append(args, *filter.StartTo)
+		where += fmt.Sprintf(" AND start_time_at < $%d", len(args))
+	}
+	return where, args
+}
+
+// batchMissingOrConflict tells why an operation on a task of the user
+// matched no rows.
+func (r *TaskPostgres) batchMissingOrConflict(ctx context.Context, telegramId, taskId int) error {
+	var id int
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = $1 AND telegram_id = $2", tasksTable)
+	err := r.db.GetContext(ctx, &id, query, taskId, telegramId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
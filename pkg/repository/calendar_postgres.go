@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+type CalendarPostgres struct {
+	db DBTX
+}
+
+func NewCalendarPostgres(db DBTX) *CalendarPostgres {
+	return &CalendarPostgres{db: db}
+}
+
+// GetTokenHash returns the feed token hash of the user.
+func (r *CalendarPostgres) GetTokenHash(ctx context.Context, telegramId int) (hash string, err error) {
+	query := fmt.Sprintf("SELECT token_hash FROM %s WHERE telegram_id = $1", calendarFeedsTable)
+	ctx, span := startSpan(ctx, calendarFeedsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &hash, query, telegramId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return hash, err
+}
+
+// SetTokenHash replaces the feed token of the user.
+func (r *CalendarPostgres) SetTokenHash(ctx context.Context, telegramId int, hash string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (telegram_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = CURRENT_TIMESTAMP`, calendarFeedsTable)
+	_, err := execContext(ctx, r.db, calendarFeedsTable, "INSERT", query, telegramId, hash)
+	return err
+}
+
+func (r *CalendarPostgres) DeleteToken(ctx context.Context, telegramId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE telegram_id = $1", calendarFeedsTable)
+	res, err := execContext(ctx, r.db, calendarFeedsTable, "DELETE", query, telegramId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *CalendarPostgres) CreateSeries(ctx context.Context, series task_manager.TaskSeries) (id int, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (telegram_id, recurrence, start_time_at) VALUES ($1, $2, $3) RETURNING id", taskSeriesTable)
+	ctx, span := startSpan(ctx, taskSeriesTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &id, query, series.TelegramId, series.Recurrence, series.StartTimeAt)
+	return id, err
+}
+
+// GetSeries returns the series of the user that still have tasks.
+func (r *CalendarPostgres) GetSeries(ctx context.Context, telegramId int) (series []task_manager.TaskSeries, err error) {
+	query := fmt.Sprintf(`SELECT s.id, s.telegram_id, s.recurrence, s.start_time_at FROM %s s
+		WHERE s.telegram_id = $1 AND EXISTS(SELECT 1 FROM %s t WHERE t.series_id = s.id) ORDER BY s.id`, taskSeriesTable, tasksTable)
+	ctx, span := startSpan(ctx, taskSeriesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &series, query, telegramId)
+	return series, err
+}
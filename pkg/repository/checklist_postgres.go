@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const checklistColumns = "id, task_id, text, done, position, created_at, done_at"
+
+type ChecklistPostgres struct {
+	db DBTX
+}
+
+func NewChecklistPostgres(db DBTX) *ChecklistPostgres {
+	return &ChecklistPostgres{db: db}
+}
+
+func (r *ChecklistPostgres) GetAll(ctx context.Context, taskId int) (items []task_manager.ChecklistItem, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE task_id = $1 ORDER BY position, id", checklistColumns, checklistItemsTable)
+	ctx, span := startSpan(ctx, checklistItemsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &items, query, taskId)
+	return items, err
+}
+
+func (r *ChecklistPostgres) GetById(ctx context.Context, taskId, itemId int) (item task_manager.ChecklistItem, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE task_id = $1 AND id = $2", checklistColumns, checklistItemsTable)
+	ctx, span := startSpan(ctx, checklistItemsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &item, query, taskId, itemId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return item, err
+}
+
+// Create adds the item at position or after the last item of the task. Run
+// it in a transaction, see lockChecklistPositions.
+func (r *ChecklistPostgres) Create(ctx context.Context, taskId int, input task_manager.ChecklistItemInput) (item task_manager.ChecklistItem, err error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (task_id, text, position)
+		VALUES ($1, $2, COALESCE($3, (SELECT COALESCE(max(position), 0) + 1 FROM %[1]s WHERE task_id = $1)))
+		RETURNING %[2]s`, checklistItemsTable, checklistColumns)
+	if err = lock(ctx, r.db, lockChecklistPositions, strconv.Itoa(taskId)); err != nil {
+		return item, err
+	}
+	ctx, span := startSpan(ctx, checklistItemsTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &item, query, taskId, input.Text, input.Position)
+	return item, err
+}
+
+func (r *ChecklistPostgres) Update(ctx context.Context, taskId, itemId int, input task_manager.UpdateChecklistItemInput) (item task_manager.ChecklistItem, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET text = COALESCE($1, text), done = COALESCE($2, done),
+		done_at = CASE WHEN NOT COALESCE($2, done) THEN NULL WHEN done THEN done_at ELSE CURRENT_TIMESTAMP END
+		WHERE task_id = $3 AND id = $4 RETURNING %s`, checklistItemsTable, checklistColumns)
+	ctx, span := startSpan(ctx, checklistItemsTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &item, query, input.Text, input.Done, taskId, itemId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return item, err
+}
+
+// Toggle flips done of the item in one statement, so concurrent toggles
+// are not lost.
+func (r *ChecklistPostgres) Toggle(ctx context.Context, taskId, itemId int) (item task_manager.ChecklistItem, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET done = NOT done, done_at = CASE WHEN done THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE task_id = $1 AND id = $2 RETURNING %s`, checklistItemsTable, checklistColumns)
+	ctx, span := startSpan(ctx, checklistItemsTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &item, query, taskId, itemId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return item, err
+}
+
+func (r *ChecklistPostgres) Delete(ctx context.Context, taskId, itemId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE task_id = $1 AND id = $2", checklistItemsTable)
+	res, err := execContext(ctx, r.db, checklistItemsTable, "DELETE", query, taskId, itemId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Reorder sets the positions of the items of the task to the order of
+// itemIds, which must hold every item of the task once. Run it in a
+// transaction, see lockChecklistPositions.
+func (r *ChecklistPostgres) Reorder(ctx context.Context, taskId int, itemIds []int) error {
+	if err := lock(ctx, r.db, lockChecklistPositions, strconv.Itoa(taskId)); err != nil {
+		return err
+	}
+	current, err := queryIds(ctx, r.db, checklistItemsTable, "SELECT",
+		fmt.Sprintf("SELECT id FROM %s WHERE task_id = $1", checklistItemsTable), taskId)
+	if err != nil {
+		return err
+	}
+	if !sameIds(current, itemIds) {
+		return ErrInvalidOrder
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET position = array_position($2::int[], id)
+		WHERE task_id = $1 AND position IS DISTINCT FROM array_position($2::int[], id)`, checklistItemsTable)
+	_, err = execContext(ctx, r.db, checklistItemsTable, "UPDATE", query, taskId, intArray(itemIds))
+	return err
+}
+
+// Progress counts all and done items of the task.
+func (r *ChecklistPostgres) Progress(ctx context.Context, taskId int) (total int, done int, err error) {
+	query := fmt.Sprintf("SELECT count(*), count(*) FILTER (WHERE done) FROM %s WHERE task_id = $1", checklistItemsTable)
+	ctx, span := startSpan(ctx, checklistItemsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx, query, taskId).Scan(&total, &done)
+	return
+}
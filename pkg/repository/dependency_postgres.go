@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+type DependencyPostgres struct {
+	db DBTX
+}
+
+func NewDependencyPostgres(db DBTX) *DependencyPostgres {
+	return &DependencyPostgres{db: db}
+}
+
+func (r *DependencyPostgres) Add(ctx context.Context, taskId, blockedById int) error {
+	query := fmt.Sprintf("INSERT INTO %s (task_id, blocked_by_id) VALUES ($1, $2)", taskDependenciesTable)
+	_, err := execContext(ctx, r.db, taskDependenciesTable, "INSERT", query, taskId, blockedById)
+	if isUniqueViolation(err) {
+		err = ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *DependencyPostgres) Remove(ctx context.Context, taskId, blockedById int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE task_id = $1 AND blocked_by_id = $2", taskDependenciesTable)
+	res, err := execContext(ctx, r.db, taskDependenciesTable, "DELETE", query, taskId, blockedById)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByOwner returns the dependencies between all tasks of the user.
+func (r *DependencyPostgres) GetByOwner(ctx context.Context, telegramId int) (deps []task_manager.TaskDependency, err error) {
+	query := fmt.Sprintf(`SELECT d.task_id, d.blocked_by_id FROM %s d JOIN %s t ON t.id = d.task_id
+		WHERE t.telegram_id = $1`, taskDependenciesTable, tasksTable)
+	ctx, span := startSpan(ctx, taskDependenciesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &deps, query, telegramId)
+	return deps, err
+}
+
+// GetByList returns the dependencies with a task of the list on either side.
+func (r *DependencyPostgres) GetByList(ctx context.Context, listId int) (deps []task_manager.TaskDependency, err error) {
+	query := fmt.Sprintf(`SELECT d.task_id, d.blocked_by_id FROM %s d
+		JOIN %s t ON t.id = d.task_id JOIN %[2]s b ON b.id = d.blocked_by_id
+		WHERE t.list_id = $1 OR b.list_id = $1 ORDER BY d.task_id, d.blocked_by_id`, taskDependenciesTable, tasksTable)
+	ctx, span := startSpan(ctx, taskDependenciesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &deps, query, listId)
+	return deps, err
+}
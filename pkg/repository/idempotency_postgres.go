@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+type IdempotencyPostgres struct {
+	db DBTX
+}
+
+func NewIdempotencyPostgres(db DBTX) *IdempotencyPostgres {
+	return &IdempotencyPostgres{db: db}
+}
+
+// Reserve stores the fingerprint for the key unless a live record exists.
+// It returns the existing record and false when the key is taken.
+func (r *IdempotencyPostgres) Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (record task_manager.IdempotencyRecord, reserved bool, err error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (principal, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + $4 * interval '1 second')
+		ON CONFLICT (principal, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, response = NULL,
+			created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE %[1]s.expires_at < CURRENT_TIMESTAMP
+		RETURNING principal, key, fingerprint, status_code, content_type, response`, idempotencyKeysTable)
+	ctx, span := startSpan(ctx, idempotencyKeysTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &record, query, principal, key, fingerprint, ttl.Seconds())
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return record, false, err
+	}
+
+	query = fmt.Sprintf(`SELECT principal, key, fingerprint, status_code, content_type, response
+		FROM %s WHERE principal = $1 AND key = $2`, idempotencyKeysTable)
+	err = r.db.GetContext(ctx, &record, query, principal, key)
+	return record, false, err
+}
+
+func (r *IdempotencyPostgres) Complete(ctx context.Context, principal, key string, statusCode int, contentType string, response []byte) (err error) {
+	query := fmt.Sprintf(`UPDATE %s SET status_code = $3, content_type = $4, response = $5
+		WHERE principal = $1 AND key = $2`, idempotencyKeysTable)
+	ctx, span := startSpan(ctx, idempotencyKeysTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, query, principal, key, statusCode, contentType, response)
+	return err
+}
+
+func (r *IdempotencyPostgres) Release(ctx context.Context, principal, key string) (err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE principal = $1 AND key = $2 AND status_code IS NULL", idempotencyKeysTable)
+	ctx, span := startSpan(ctx, idempotencyKeysTable, "DELETE", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, query, principal, key)
+	return err
+}
+
+func (r *IdempotencyPostgres) DeleteExpired(ctx context.Context) (deleted int64, err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < CURRENT_TIMESTAMP", idempotencyKeysTable)
+	ctx, span := startSpan(ctx, idempotencyKeysTable, "DELETE", query)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"slices"
+	"strconv"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const listColumns = `l.id, l.telegram_id, l.title, l.position, l.created_at, l.archived_at,
+	(SELECT count(*) FROM tasks t WHERE t.list_id = l.id) AS task_count`
+
+// ErrInvalidOrder rejects an order that does not hold every item once.
+var ErrInvalidOrder = errors.New("order must contain every item exactly once")
+
+type ListPostgres struct {
+	db DBTX
+}
+
+func NewListPostgres(db DBTX) *ListPostgres {
+	return &ListPostgres{db: db}
+}
+
+func (r *ListPostgres) GetAll(ctx context.Context, telegramId int, archived bool) (lists []task_manager.List, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s l WHERE l.telegram_id = $1 AND ($2 OR l.archived_at IS NULL)
+		ORDER BY l.position, l.id`, listColumns, listsTable)
+	ctx, span := startSpan(ctx, listsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &lists, query, telegramId, archived)
+	return lists, err
+}
+
+func (r *ListPostgres) GetById(ctx context.Context, listId int) (list task_manager.List, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s l WHERE l.id = $1", listColumns, listsTable)
+	ctx, span := startSpan(ctx, listsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &list, query, listId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return list, err
+}
+
+// Create adds the list after the other lists of the user. Run it in a
+// transaction, see lockListPositions.
+func (r *ListPostgres) Create(ctx context.Context, telegramId int, input task_manager.ListInput) (list task_manager.List, err error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (telegram_id, title, position)
+		VALUES ($1, $2, (SELECT COALESCE(max(position), 0) + 1 FROM %[1]s WHERE telegram_id = $1)) RETURNING id`, listsTable)
+	if err = lock(ctx, r.db, lockListPositions, strconv.Itoa(telegramId)); err != nil {
+		return list, err
+	}
+	ctx, span := startSpan(ctx, listsTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	var id int
+	if err = r.db.QueryRowContext(ctx, query, telegramId, input.Title).Scan(&id); err != nil {
+		return list, err
+	}
+	return r.GetById(ctx, id)
+}
+
+func (r *ListPostgres) Update(ctx context.Context, listId int, input task_manager.UpdateListInput) (list task_manager.List, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET title = COALESCE($1, title), position = COALESCE($2, position)
+		WHERE id = $3`, listsTable)
+	res, err := execContext(ctx, r.db, listsTable, "UPDATE", query, input.Title, input.Position, listId)
+	if err != nil {
+		return list, err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return list, ErrNotFound
+	}
+	return r.GetById(ctx, listId)
+}
+
+// Delete removes the list; its tasks stay without a list.
+// Delete deletes the list. Its tasks leave the archive with it and move
+// out of lists after the other tasks of the user. Run it in a transaction,
+// since it locks task positions.
+func (r *ListPostgres) Delete(ctx context.Context, listId int) (err error) {
+	list, err := r.GetById(ctx, listId)
+	if err != nil {
+		return err
+	}
+	if err = lock(ctx, r.db, lockTaskPositions, strconv.Itoa(list.TelegramId)); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`UPDATE %[1]s SET list_id = NULL, archived_at = NULL,
+		position = position + (SELECT COALESCE(max(position), 0) FROM %[1]s WHERE telegram_id = $2 AND list_id IS NULL)
+		WHERE list_id = $1`, tasksTable)
+	if _, err = execContext(ctx, r.db, tasksTable, "UPDATE", query, listId, list.TelegramId); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", listsTable)
+	res, err := execContext(ctx, r.db, listsTable, "DELETE", query, listId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Archive archives or restores the list together with all its tasks. Run
+// it in a transaction to change both at once.
+func (r *ListPostgres) Archive(ctx context.Context, listId int, archived bool) (list task_manager.List, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET archived_at = CASE WHEN $1 THEN CURRENT_TIMESTAMP END
+		WHERE id = $2 AND (archived_at IS NULL) = $1`, listsTable)
+	if _, err = execContext(ctx, r.db, listsTable, "UPDATE", query, archived, listId); err != nil {
+		return list, err
+	}
+
+	query = fmt.Sprintf(`UPDATE %s t SET archived_at = l.archived_at FROM %s l
+		WHERE l.id = $1 AND t.list_id = l.id AND t.archived_at IS DISTINCT FROM l.archived_at`, tasksTable, listsTable)
+	if _, err = execContext(ctx, r.db, tasksTable, "UPDATE", query, listId); err != nil {
+		return list, err
+	}
+	return r.GetById(ctx, listId)
+}
+
+// Reorder sets the positions of the tasks of the list to the order of
+// taskIds, which must hold every task of the list once. Run it in a
+// transaction, see lockTaskPositions.
+func (r *ListPostgres) Reorder(ctx context.Context, listId int, taskIds []int) error {
+	list, err := r.GetById(ctx, listId)
+	if err != nil {
+		return err
+	}
+	if err = lock(ctx, r.db, lockTaskPositions, strconv.Itoa(list.TelegramId)); err != nil {
+		return err
+	}
+	current, err := queryIds(ctx, r.db, tasksTable, "SELECT", fmt.Sprintf("SELECT id FROM %s WHERE list_id = $1", tasksTable), listId)
+	if err != nil {
+		return err
+	}
+	if !sameIds(current, taskIds) {
+		return ErrInvalidOrder
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET position = array_position($2::int[], id)
+		WHERE list_id = $1 AND position IS DISTINCT FROM array_position($2::int[], id)`, tasksTable)
+	_, err = execContext(ctx, r.db, tasksTable, "UPDATE", query, listId, intArray(taskIds))
+	return err
+}
+
+// sameIds tells whether ids holds every id of current exactly once.
+func sameIds(current, ids []int) bool {
+	if len(current) != len(ids) {
+		return false
+	}
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	slices.Sort(current)
+	return slices.Equal(current, sorted)
+}
+
+func intArray(ids []int) pq.Int64Array {
+	array := make(pq.Int64Array, len(ids))
+	for i, id := range ids {
+		array[i] = int64(id)
+	}
+	return array
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const outboxColumns = "id, event_type, telegram_id, payload, created_at, attempts, txid"
+
+// outboxCommitted limits reads to events of transactions older than every
+// running one. Those events are final: no transaction that is still running
+// can add an event before them in commit order.
+const outboxCommitted = "txid < txid_snapshot_xmin(txid_current_snapshot())"
+
+type OutboxPostgres struct {
+	db DBTX
+}
+
+func NewOutboxPostgres(db DBTX) *OutboxPostgres {
+	return &OutboxPostgres{db: db}
+}
+
+// Add stores the event; run it in the transaction of the change it describes.
+func (r *OutboxPostgres) Add(ctx context.Context, event task_manager.Event) (id int64, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (event_type, telegram_id, payload) VALUES ($1, $2, $3) RETURNING id", outboxTable)
+	ctx, span := startSpan(ctx, outboxTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx, query, event.Type, event.TelegramId, []byte(event.Payload)).Scan(&id)
+	return id, err
+}
+
+// ClaimPending takes up to limit events due for delivery, counting the
+// attempt and hiding them from other dispatchers for lease. Events of a
+// dispatcher that crashed are claimed again once the lease expires.
+func (r *OutboxPostgres) ClaimPending(ctx context.Context, limit int, lease time.Duration) (events []task_manager.Event, err error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET attempts = attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM %[1]s WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY id LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) RETURNING %[2]s`, outboxTable, outboxColumns)
+	ctx, span := startSpan(ctx, outboxTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	if err = r.db.SelectContext(ctx, &events, query, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *OutboxPostgres) MarkDelivered(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("UPDATE %s SET delivered_at = CURRENT_TIMESTAMP, last_error = NULL WHERE id = $1", outboxTable)
+	_, err := execContext(ctx, r.db, outboxTable, "UPDATE", query, id)
+	return err
+}
+
+// MarkFailed schedules the next delivery attempt of the event after delay.
+func (r *OutboxPostgres) MarkFailed(ctx context.Context, id int64, delay time.Duration, reason string) error {
+	query := fmt.Sprintf(`UPDATE %s SET next_attempt_at = CURRENT_TIMESTAMP + $2 * interval '1 second', last_error = $3
+		WHERE id = $1`, outboxTable)
+	_, err := execContext(ctx, r.db, outboxTable, "UPDATE", query, id, delay.Seconds(), reason)
+	return err
+}
+
+// MarkDead moves the event to the dead letters, which are never retried.
+func (r *OutboxPostgres) MarkDead(ctx context.Context, id int64, reason string) error {
+	query := fmt.Sprintf("UPDATE %s SET dead_at = CURRENT_TIMESTAMP, last_error = $2 WHERE id = $1", outboxTable)
+	_, err := execContext(ctx, r.db, outboxTable, "UPDATE", query, id, reason)
+	return err
+}
+
+// Prune deletes up to limit delivered or dead events created before.
+func (r *OutboxPostgres) Prune(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %[1]s WHERE id IN (
+			SELECT id FROM %[1]s WHERE created_at < $1 AND (delivered_at IS NOT NULL OR dead_at IS NOT NULL)
+			LIMIT $2
+		)`, outboxTable)
+	res, err := execContext(ctx, r.db, outboxTable, "DELETE", query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *OutboxPostgres) GetById(ctx context.Context, id int64) (event task_manager.Event, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", outboxColumns, outboxTable)
+	ctx, span := startSpan(ctx, outboxTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &event, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return event, err
+}
+
+// LastPosition returns the position of the latest final event, zero if
+// there are none.
+func (r *OutboxPostgres) LastPosition(ctx context.Context) (position task_manager.StreamPosition, err error) {
+	query := fmt.Sprintf("SELECT txid, id FROM %s WHERE %s ORDER BY txid DESC, id DESC LIMIT 1", outboxTable, outboxCommitted)
+	ctx, span := startSpan(ctx, outboxTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx, query).Scan(&position.Txid, &position.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	return position, err
+}
+
+// GetAfter returns up to limit final events following after in commit
+// order.
+func (r *OutboxPostgres) GetAfter(ctx context.Context, after task_manager.StreamPosition, limit int) (events []task_manager.Event, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE (txid, id) > ($1, $2) AND %s
+		ORDER BY txid, id LIMIT $3`, outboxColumns, outboxTable, outboxCommitted)
+	ctx, span := startSpan(ctx, outboxTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	if err = r.db.SelectContext(ctx, &events, query, after.Txid, after.Id, limit); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetByTelegramId returns up to limit events of the user following after
+// up to until in commit order.
+func (r *OutboxPostgres) GetByTelegramId(ctx context.Context, telegramId int, after, until task_manager.StreamPosition, limit int) (events []task_manager.Event, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE telegram_id = $1 AND (txid, id) > ($2, $3) AND (txid, id) <= ($4, $5)
+		ORDER BY txid, id LIMIT $6`, outboxColumns, outboxTable)
+	ctx, span := startSpan(ctx, outboxTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	if err = r.db.SelectContext(ctx, &events, query, telegramId, after.Txid, after.Id, until.Txid, until.Id, limit); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -4,20 +4,52 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v4"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
-	"io/ioutil"
+	"github.com/lib/pq"
 	"log/slog"
 	"os"
 )
 
 const (
-	tasksTable = "tasks"
+	tasksTable             = "tasks"
+	userTiersTable         = "user_tiers"
+	idempotencyKeysTable   = "idempotency_keys"
+	taskRemindersTable     = "task_reminders"
+	tagsTable              = "tags"
+	tasksTagsTable         = "tasks_tags"
+	listsTable             = "lists"
+	checklistItemsTable    = "checklist_items"
+	taskDependenciesTable  = "task_dependencies"
+	taskTemplatesTable     = "task_templates"
+	calendarFeedsTable     = "calendar_feeds"
+	taskSeriesTable        = "task_series"
+	outboxTable            = "outbox"
+	webhooksTable          = "webhooks"
+	webhookDeliveriesTable = "webhook_deliveries"
+)
+
+// Scopes of advisory locks taken with Repository.Lock.
+const (
+	LockQuota        = "quota"
+	LockDependencies = "dependencies"
+
+	// Statements appending at max(position) + 1 take these locks first, so
+	// concurrent inserts get distinct positions; they must run in Do.
+	lockTaskPositions      = "task positions"
+	lockListPositions      = "list positions"
+	lockChecklistPositions = "checklist positions"
+)
+
+const (
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
 )
 
 type Config struct {
@@ -28,28 +60,45 @@ type Config struct {
 	DBName   string
 }
 
-func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
-	slog.Info("init database",
-		"cfg", cfg)
+// LogValue keeps the password out of the logs.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", c.HOST),
+		slog.String("port", c.PORT),
+		slog.String("user", c.Username),
+		slog.String("dbname", c.DBName),
+		slog.String("password", "[REDACTED]"),
+	)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// isRetryable tells whether a transaction failed only because of
+// concurrent ones and may succeed when run again.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected)
+}
+
+func NewPostgresDB(cfg Config, logger *slog.Logger) (*sqlx.DB, error) {
+	logger.Info("init database", "cfg", cfg)
 	rootCertPool := x509.NewCertPool()
 	pathSert := "./root.crt"
-	slog.Info(pathSert)
-	slog.Info("перед чтением файла")
-	pem, err := ioutil.ReadFile(pathSert)
+	pem, err := os.ReadFile(pathSert)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("read root certificate: %w", err)
 	}
-	slog.Info("после чтения файла")
 	if ok := rootCertPool.AppendCertsFromPEM(pem); !ok {
-		panic("Failed to append PEM.")
+		return nil, errors.New("failed to append PEM")
 	}
 	connString := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=verify-full sslrootcert=%s target_session_attrs=read-write",
 		cfg.HOST, cfg.PORT, cfg.Username, cfg.DBName, cfg.Password, pathSert)
-	slog.Info(connString)
 	connConfig, err := pgx.ParseConfig(connString)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to parse config: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to parse config: %w", err)
 	}
 	connConfig.TLSConfig = &tls.Config{
 		RootCAs:            rootCertPool,
@@ -57,8 +106,7 @@ func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
 	}
 	conn, err := pgx.ConnectConfig(context.Background(), connConfig)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
 	defer conn.Close(context.Background())
@@ -67,11 +115,10 @@ func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
 
 	err = conn.QueryRow(context.Background(), "select version()").Scan(&version)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "QueryRow failed: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("query server version: %w", err)
 	}
 
-	fmt.Println(version)
+	logger.Info("connected to database", "version", version)
 
 	db, err := sqlx.Open("postgres", connString)
 	if err != nil {
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+type QuotaPostgres struct {
+	db DBTX
+}
+
+func NewQuotaPostgres(db DBTX) *QuotaPostgres {
+	return &QuotaPostgres{db: db}
+}
+
+// GetTier returns the tier assigned to the user or an empty string.
+func (r *QuotaPostgres) GetTier(ctx context.Context, telegramId int) (tier string, err error) {
+	query := fmt.Sprintf("SELECT tier FROM %s WHERE telegram_id = $1", userTiersTable)
+	ctx, span := startSpan(ctx, userTiersTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &tier, query, telegramId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tier, err
+}
+
+// CountUsage counts unfinished tasks of the user and tasks created since
+// the start of the current day.
+func (r *QuotaPostgres) CountUsage(ctx context.Context, telegramId int) (active int, today int, err error) {
+	query := fmt.Sprintf(`SELECT
+		count(*) FILTER (WHERE status_end = $2),
+		count(*) FILTER (WHERE created_at >= date_trunc('day', CURRENT_TIMESTAMP))
+		FROM %s WHERE telegram_id = $1`, tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx, query, telegramId, task_manager.Start).Scan(&active, &today)
+	return
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const (
+	reminderColumns = "r.id, r.task_id, r.remind_at, r.offset_seconds, r.fired_at, r.created_at"
+	// reminderFireAt resolves offset reminders against the task time.
+	reminderFireAt = "COALESCE(r.remind_at, COALESCE(t.due_at, t.start_time_at) + r.offset_seconds * interval '1 second')"
+)
+
+type ReminderPostgres struct {
+	db DBTX
+}
+
+func NewReminderPostgres(db DBTX) *ReminderPostgres {
+	return &ReminderPostgres{db: db}
+}
+
+func (r *ReminderPostgres) Create(ctx context.Context, taskId int, input task_manager.TaskReminderInput) (reminder task_manager.TaskReminder, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (task_id, remind_at, offset_seconds) VALUES ($1, $2, $3) RETURNING id`, taskRemindersTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	var id int
+	if err = r.db.QueryRowContext(ctx, query, taskId, input.RemindAt, input.OffsetSeconds).Scan(&id); err != nil {
+		return reminder, err
+	}
+	return r.GetById(ctx, taskId, id)
+}
+
+func (r *ReminderPostgres) GetAll(ctx context.Context, taskId int) (reminders []task_manager.TaskReminder, err error) {
+	query := fmt.Sprintf(`SELECT %s, %s AS fire_at FROM %s r JOIN %s t ON t.id = r.task_id
+		WHERE r.task_id = $1 ORDER BY fire_at, r.id`, reminderColumns, reminderFireAt, taskRemindersTable, tasksTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &reminders, query, taskId)
+	return reminders, err
+}
+
+func (r *ReminderPostgres) GetById(ctx context.Context, taskId, reminderId int) (reminder task_manager.TaskReminder, err error) {
+	query := fmt.Sprintf(`SELECT %s, %s AS fire_at FROM %s r JOIN %s t ON t.id = r.task_id
+		WHERE r.task_id = $1 AND r.id = $2`, reminderColumns, reminderFireAt, taskRemindersTable, tasksTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &reminder, query, taskId, reminderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return reminder, err
+}
+
+// Update replaces the trigger of the reminder and re-arms it.
+func (r *ReminderPostgres) Update(ctx context.Context, taskId, reminderId int, input task_manager.TaskReminderInput) (reminder task_manager.TaskReminder, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET remind_at = $1, offset_seconds = $2, fired_at = NULL
+		WHERE task_id = $3 AND id = $4`, taskRemindersTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, query, input.RemindAt, input.OffsetSeconds, taskId, reminderId)
+	if err != nil {
+		return reminder, err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return reminder, ErrNotFound
+	}
+	return r.GetById(ctx, taskId, reminderId)
+}
+
+func (r *ReminderPostgres) Delete(ctx context.Context, taskId, reminderId int) (err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE task_id = $1 AND id = $2", taskRemindersTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "DELETE", query)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, query, taskId, reminderId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimDue marks up to limit due reminders of unfinished, unarchived tasks
+// as fired and returns them. Reminders of blocked tasks wait until the
+// blockers are done. Rows locked by another instance are skipped, so every
+// reminder is claimed once.
+func (r *ReminderPostgres) ClaimDue(ctx context.Context, limit int) (reminders []task_manager.FiredReminder, err error) {
+	query := fmt.Sprintf(`WITH due AS (
+			SELECT r.id FROM %[1]s r JOIN %[2]s t ON t.id = r.task_id
+			WHERE r.fired_at IS NULL AND t.status_end = $1 AND t.archived_at IS NULL AND %[3]s <= CURRENT_TIMESTAMP
+				AND NOT EXISTS (SELECT 1 FROM %[5]s d JOIN %[2]s b ON b.id = d.blocked_by_id
+					WHERE d.task_id = t.id AND b.status_end = $1)
+			ORDER BY r.id LIMIT $2
+			FOR UPDATE OF r SKIP LOCKED
+		), fired AS (
+			UPDATE %[1]s r SET fired_at = CURRENT_TIMESTAMP FROM due WHERE r.id = due.id
+			RETURNING r.*
+		)
+		SELECT %[4]s, %[3]s AS fire_at, t.telegram_id, t.text
+		FROM fired r JOIN %[2]s t ON t.id = r.task_id`,
+		taskRemindersTable, tasksTable, reminderFireAt, reminderColumns, taskDependenciesTable)
+	ctx, span := startSpan(ctx, taskRemindersTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &reminders, query, task_manager.Start, limit)
+	return reminders, err
+}
@@ -1,23 +1,171 @@
 package repository
 
 import (
+	"context"
 	"github.com/jmoiron/sqlx"
+	"log/slog"
 	"task_manager"
+	"task_manager/pkg/metrics"
+	"time"
 )
 
 type TaskManagerTask interface {
-	Create(task task_manager.CreateTaskInput, status task_manager.StatusEnd) (int, error)
-	GetAll(telegramId int) ([]task_manager.Task, error)
-	GetById(taskId int) (task_manager.Task, error)
-	Delete(taskId int) error
+	Create(ctx context.Context, task task_manager.CreateTaskInput, status task_manager.StatusEnd) (int, error)
+	GetAll(ctx context.Context, telegramId int, filter task_manager.TaskFilter) ([]task_manager.Task, error)
+	GetById(ctx context.Context, taskId int) (task_manager.Task, error)
+	GetByIds(ctx context.Context, taskIds []int) ([]task_manager.Task, error)
+	Update(ctx context.Context, taskId int, input task_manager.UpdateTaskInput, version int) (task_manager.Task, error)
+	Complete(ctx context.Context, taskId int, version int) (task_manager.Task, error)
+	Move(ctx context.Context, taskId int, listId, position *int, version int) (task_manager.Task, error)
+	Delete(ctx context.Context, taskId int, version int) error
+	Batch(ctx context.Context, telegramId int, ops []task_manager.BatchOperation, atomic bool) ([]task_manager.BatchItemResult, error)
+	Export(ctx context.Context, telegramId int, fn func(task_manager.Task) error) error
+	Import(ctx context.Context, telegramId int, records []task_manager.TaskRecord) ([]int, error)
+	CountActive(ctx context.Context) (pending int, overdue int, err error)
+}
+
+type Quota interface {
+	GetTier(ctx context.Context, telegramId int) (string, error)
+	CountUsage(ctx context.Context, telegramId int) (active int, today int, err error)
+}
+
+type IdempotencyKey interface {
+	Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (task_manager.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, principal, key string, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, principal, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type TaskReminder interface {
+	Create(ctx context.Context, taskId int, input task_manager.TaskReminderInput) (task_manager.TaskReminder, error)
+	GetAll(ctx context.Context, taskId int) ([]task_manager.TaskReminder, error)
+	GetById(ctx context.Context, taskId, reminderId int) (task_manager.TaskReminder, error)
+	Update(ctx context.Context, taskId, reminderId int, input task_manager.TaskReminderInput) (task_manager.TaskReminder, error)
+	Delete(ctx context.Context, taskId, reminderId int) error
+	ClaimDue(ctx context.Context, limit int) ([]task_manager.FiredReminder, error)
+}
+
+type Tag interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.Tag, error)
+	GetById(ctx context.Context, tagId int) (task_manager.Tag, error)
+	Create(ctx context.Context, telegramId int, name string) (task_manager.Tag, error)
+	Rename(ctx context.Context, tagId int, name string) (task_manager.Tag, error)
+	Merge(ctx context.Context, fromId, intoId int) (task_manager.Tag, error)
+	Delete(ctx context.Context, tagId int) error
+	SetTaskTags(ctx context.Context, telegramId, taskId int, names []string) error
+}
+
+type List interface {
+	GetAll(ctx context.Context, telegramId int, archived bool) ([]task_manager.List, error)
+	GetById(ctx context.Context, listId int) (task_manager.List, error)
+	Create(ctx context.Context, telegramId int, input task_manager.ListInput) (task_manager.List, error)
+	Update(ctx context.Context, listId int, input task_manager.UpdateListInput) (task_manager.List, error)
+	Delete(ctx context.Context, listId int) error
+	Archive(ctx context.Context, listId int, archived bool) (task_manager.List, error)
+	Reorder(ctx context.Context, listId int, taskIds []int) error
+}
+
+type Checklist interface {
+	GetAll(ctx context.Context, taskId int) ([]task_manager.ChecklistItem, error)
+	GetById(ctx context.Context, taskId, itemId int) (task_manager.ChecklistItem, error)
+	Create(ctx context.Context, taskId int, input task_manager.ChecklistItemInput) (task_manager.ChecklistItem, error)
+	Update(ctx context.Context, taskId, itemId int, input task_manager.UpdateChecklistItemInput) (task_manager.ChecklistItem, error)
+	Toggle(ctx context.Context, taskId, itemId int) (task_manager.ChecklistItem, error)
+	Delete(ctx context.Context, taskId, itemId int) error
+	Reorder(ctx context.Context, taskId int, itemIds []int) error
+	Progress(ctx context.Context, taskId int) (total int, done int, err error)
+}
+
+type Dependency interface {
+	Add(ctx context.Context, taskId, blockedById int) error
+	Remove(ctx context.Context, taskId, blockedById int) error
+	GetByOwner(ctx context.Context, telegramId int) ([]task_manager.TaskDependency, error)
+	GetByList(ctx context.Context, listId int) ([]task_manager.TaskDependency, error)
+}
+
+type Template interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.TaskTemplate, error)
+	GetById(ctx context.Context, templateId int) (task_manager.TaskTemplate, error)
+	Create(ctx context.Context, telegramId int, input task_manager.TaskTemplateInput) (task_manager.TaskTemplate, error)
+	Update(ctx context.Context, templateId int, input task_manager.TaskTemplateInput) (task_manager.TaskTemplate, error)
+	Delete(ctx context.Context, templateId int) error
+}
+
+type Calendar interface {
+	GetTokenHash(ctx context.Context, telegramId int) (string, error)
+	SetTokenHash(ctx context.Context, telegramId int, hash string) error
+	DeleteToken(ctx context.Context, telegramId int) error
+	CreateSeries(ctx context.Context, series task_manager.TaskSeries) (int, error)
+	GetSeries(ctx context.Context, telegramId int) ([]task_manager.TaskSeries, error)
+}
+
+type Outbox interface {
+	Add(ctx context.Context, event task_manager.Event) (int64, error)
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]task_manager.Event, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, delay time.Duration, reason string) error
+	MarkDead(ctx context.Context, id int64, reason string) error
+	Prune(ctx context.Context, before time.Time, limit int) (int64, error)
+	GetById(ctx context.Context, id int64) (task_manager.Event, error)
+	LastPosition(ctx context.Context) (task_manager.StreamPosition, error)
+	GetAfter(ctx context.Context, after task_manager.StreamPosition, limit int) ([]task_manager.Event, error)
+	GetByTelegramId(ctx context.Context, telegramId int, after, until task_manager.StreamPosition, limit int) ([]task_manager.Event, error)
+}
+
+type Webhook interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.Webhook, error)
+	GetById(ctx context.Context, webhookId int) (task_manager.Webhook, error)
+	Create(ctx context.Context, telegramId int, input task_manager.WebhookInput) (task_manager.Webhook, error)
+	Update(ctx context.Context, webhookId int, input task_manager.WebhookInput) (task_manager.Webhook, error)
+	Delete(ctx context.Context, webhookId int) error
+	Enqueue(ctx context.Context, event task_manager.Event, body []byte) (int64, error)
+	CreateDelivery(ctx context.Context, webhookId int, eventType task_manager.EventType, body []byte, lease time.Duration) (task_manager.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookId int, limit int) ([]task_manager.WebhookDelivery, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]task_manager.PendingDelivery, error)
+	MarkDelivered(ctx context.Context, deliveryId int64, responseCode int) error
+	MarkFailed(ctx context.Context, deliveryId int64, responseCode *int, reason string, delay time.Duration) error
+	MarkDead(ctx context.Context, deliveryId int64, responseCode *int, reason string) error
 }
 
 type Repository struct {
 	TaskManagerTask
+	Quota
+	IdempotencyKey
+	TaskReminder
+	Tag
+	List
+	Checklist
+	Dependency
+	Template
+	Calendar
+	Outbox
+	Webhook
+
+	db      DBTX
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+func NewRepository(db *sqlx.DB, m *metrics.Metrics, logger *slog.Logger) *Repository {
+	return newRepository(db, m, logger)
 }
 
-func NewRepository(db *sqlx.DB) *Repository {
+func newRepository(db DBTX, m *metrics.Metrics, logger *slog.Logger) *Repository {
 	return &Repository{
-		TaskManagerTask: NewTaskPostgres(db),
+		TaskManagerTask: NewTaskMetrics(NewTaskPostgres(db, logger), m.RepositoryDuration),
+		Quota:           NewQuotaPostgres(db),
+		IdempotencyKey:  NewIdempotencyPostgres(db),
+		TaskReminder:    NewReminderPostgres(db),
+		Tag:             NewTagPostgres(db),
+		List:            NewListPostgres(db),
+		Checklist:       NewChecklistPostgres(db),
+		Dependency:      NewDependencyPostgres(db),
+		Template:        NewTemplatePostgres(db),
+		Calendar:        NewCalendarPostgres(db),
+		Outbox:          NewOutboxPostgres(db),
+		Webhook:         NewWebhookPostgres(db),
+		db:              db,
+		metrics:         m,
+		logger:          logger,
 	}
 }
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const tagColumns = "g.id, g.telegram_id, g.name, g.created_at, (SELECT count(*) FROM tasks_tags tt WHERE tt.tag_id = g.id) AS task_count"
+
+var ErrAlreadyExists = errors.New("already exists")
+
+type TagPostgres struct {
+	db DBTX
+}
+
+func NewTagPostgres(db DBTX) *TagPostgres {
+	return &TagPostgres{db: db}
+}
+
+func (r *TagPostgres) GetAll(ctx context.Context, telegramId int) (tags []task_manager.Tag, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s g WHERE g.telegram_id = $1 ORDER BY g.name", tagColumns, tagsTable)
+	ctx, span := startSpan(ctx, tagsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &tags, query, telegramId)
+	return tags, err
+}
+
+func (r *TagPostgres) GetById(ctx context.Context, tagId int) (tag task_manager.Tag, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s g WHERE g.id = $1", tagColumns, tagsTable)
+	ctx, span := startSpan(ctx, tagsTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &tag, query, tagId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return tag, err
+}
+
+func (r *TagPostgres) Create(ctx context.Context, telegramId int, name string) (tag task_manager.Tag, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (telegram_id, name) VALUES ($1, $2) RETURNING id", tagsTable)
+	ctx, span := startSpan(ctx, tagsTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	var id int
+	if err = r.db.QueryRowContext(ctx, query, telegramId, name).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			err = ErrAlreadyExists
+		}
+		return tag, err
+	}
+	return r.GetById(ctx, id)
+}
+
+// Rename renames the tag and rewrites its hashtag in the texts of tagged tasks.
+func (r *TagPostgres) Rename(ctx context.Context, tagId int, name string) (tag task_manager.Tag, err error) {
+	old, err := r.GetById(ctx, tagId)
+	if err != nil {
+		return tag, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET name = $1 WHERE id = $2", tagsTable)
+	if _, err = execContext(ctx, r.db, tagsTable, "UPDATE", query, name, tagId); err != nil {
+		if isUniqueViolation(err) {
+			err = ErrAlreadyExists
+		}
+		return tag, err
+	}
+	if err = r.rewriteHashtag(ctx, tagId, old.Name, name); err != nil {
+		return tag, err
+	}
+	return r.GetById(ctx, tagId)
+}
+
+// Merge moves the tasks of tag fromId to tag intoId and deletes fromId.
+func (r *TagPostgres) Merge(ctx context.Context, fromId, intoId int) (tag task_manager.Tag, err error) {
+	from, err := r.GetById(ctx, fromId)
+	if err != nil {
+		return tag, err
+	}
+	into, err := r.GetById(ctx, intoId)
+	if err != nil {
+		return tag, err
+	}
+	if err = r.rewriteHashtag(ctx, fromId, from.Name, into.Name); err != nil {
+		return tag, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (task_id, tag_id) SELECT task_id, $2 FROM %[1]s WHERE tag_id = $1
+		ON CONFLICT DO NOTHING`, tasksTagsTable)
+	if _, err = execContext(ctx, r.db, tasksTagsTable, "INSERT", query, fromId, intoId); err != nil {
+		return tag, err
+	}
+	if err = r.Delete(ctx, fromId); err != nil {
+		return tag, err
+	}
+	return r.GetById(ctx, intoId)
+}
+
+func (r *TagPostgres) Delete(ctx context.Context, tagId int) (err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tagsTable)
+	ctx, span := startSpan(ctx, tagsTable, "DELETE", query)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, query, tagId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTaskTags replaces the tags of the task, creating missing tags of the user.
+func (r *TagPostgres) SetTaskTags(ctx context.Context, telegramId, taskId int, names []string) error {
+	return setTaskTags(ctx, r.db, telegramId, taskId, names)
+}
+
+func setTaskTags(ctx context.Context, db sqlx.ExecerContext, telegramId, taskId int, names []string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, name) SELECT $1, unnest($2::text[])
+		ON CONFLICT (telegram_id, name) DO NOTHING`, tagsTable)
+	if _, err := execContext(ctx, db, tagsTable, "INSERT", query, telegramId, pq.StringArray(names)); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s tt USING %s g WHERE tt.tag_id = g.id AND tt.task_id = $1
+		AND NOT (g.name = ANY($2::text[]))`, tasksTagsTable, tagsTable)
+	if _, err := execContext(ctx, db, tasksTagsTable, "DELETE", query, taskId, pq.StringArray(names)); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (task_id, tag_id) SELECT $1, g.id FROM %s g
+		WHERE g.telegram_id = $2 AND g.name = ANY($3::text[]) ON CONFLICT DO NOTHING`, tasksTagsTable, tagsTable)
+	_, err := execContext(ctx, db, tasksTagsTable, "INSERT", query, taskId, telegramId, pq.StringArray(names))
+	return err
+}
+
+// rewriteHashtag replaces #from with #to in the texts of tasks tagged tagId.
+func (r *TagPostgres) rewriteHashtag(ctx context.Context, tagId int, from, to string) error {
+	query := fmt.Sprintf(`UPDATE %s t SET text = regexp_replace(t.text, '#' || $2 || '\M', '#' || $3, 'gi')
+		FROM %s tt WHERE tt.task_id = t.id AND tt.tag_id = $1`, tasksTable, tasksTagsTable)
+	_, err := execContext(ctx, r.db, tasksTable, "UPDATE", query, tagId, from, to)
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"task_manager"
+	"time"
+)
+
+// TaskMetrics decorates TaskManagerTask with per method latency observations.
+type TaskMetrics struct {
+	next     TaskManagerTask
+	duration *prometheus.HistogramVec
+}
+
+func NewTaskMetrics(next TaskManagerTask, duration *prometheus.HistogramVec) *TaskMetrics {
+	return &TaskMetrics{next: next, duration: duration}
+}
+
+func (r *TaskMetrics) observe(method string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	r.duration.WithLabelValues(method, status).Observe(time.Since(start).Seconds())
+}
+
+func (r *TaskMetrics) Create(ctx context.Context, task task_manager.CreateTaskInput, status task_manager.StatusEnd) (id int, err error) {
+	defer func(start time.Time) { r.observe("Create", start, err) }(time.Now())
+	return r.next.Create(ctx, task, status)
+}
+
+func (r *TaskMetrics) GetAll(ctx context.Context, telegramId int, filter task_manager.TaskFilter) (tasks []task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("GetAll", start, err) }(time.Now())
+	return r.next.GetAll(ctx, telegramId, filter)
+}
+
+func (r *TaskMetrics) GetById(ctx context.Context, taskId int) (task task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("GetById", start, err) }(time.Now())
+	return r.next.GetById(ctx, taskId)
+}
+
+func (r *TaskMetrics) GetByIds(ctx context.Context, taskIds []int) (tasks []task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("GetByIds", start, err) }(time.Now())
+	return r.next.GetByIds(ctx, taskIds)
+}
+
+func (r *TaskMetrics) Update(ctx context.Context, taskId int, input task_manager.UpdateTaskInput, version int) (task task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("Update", start, err) }(time.Now())
+	return r.next.Update(ctx, taskId, input, version)
+}
+
+func (r *TaskMetrics) Complete(ctx context.Context, taskId int, version int) (task task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("Complete", start, err) }(time.Now())
+	return r.next.Complete(ctx, taskId, version)
+}
+
+func (r *TaskMetrics) Move(ctx context.Context, taskId int, listId, position *int, version int) (task task_manager.Task, err error) {
+	defer func(start time.Time) { r.observe("Move", start, err) }(time.Now())
+	return r.next.Move(ctx, taskId, listId, position, version)
+}
+
+func (r *TaskMetrics) Batch(ctx context.Context, telegramId int, ops []task_manager.BatchOperation, atomic bool) (results []task_manager.BatchItemResult, err error) {
+	defer func(start time.Time) { r.observe("Batch", start, err) }(time.Now())
+	return r.next.Batch(ctx, telegramId, ops, atomic)
+}
+
+func (r *TaskMetrics) Export(ctx context.Context, telegramId int, fn func(task_manager.Task) error) (err error) {
+	defer func(start time.Time) { r.observe("Export", start, err) }(time.Now())
+	return r.next.Export(ctx, telegramId, fn)
+}
+
+func (r *TaskMetrics) Import(ctx context.Context, telegramId int, records []task_manager.TaskRecord) (ids []int, err error) {
+	defer func(start time.Time) { r.observe("Import", start, err) }(time.Now())
+	return r.next.Import(ctx, telegramId, records)
+}
+
+func (r *TaskMetrics) Delete(ctx context.Context, taskId int, version int) (err error) {
+	defer func(start time.Time) { r.observe("Delete", start, err) }(time.Now())
+	return r.next.Delete(ctx, taskId, version)
+}
+
+func (r *TaskMetrics) CountActive(ctx context.Context) (pending int, overdue int, err error) {
+	defer func(start time.Time) { r.observe("CountActive", start, err) }(time.Now())
+	return r.next.CountActive(ctx)
+}
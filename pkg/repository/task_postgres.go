@@ -1,47 +1,277 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log/slog"
+	"strconv"
 	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const (
+	taskColumns = `id, text, status_end, created_at, updated_at, end_task_at, telegram_id, start_time_at, version,
+		priority, due_at, (status_end = 'START' AND due_at < CURRENT_TIMESTAMP) IS TRUE AS overdue,
+		ARRAY(SELECT g.name FROM tasks_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = tasks.id ORDER BY g.name) AS tags,
+		list_id, position, archived_at, checklist_auto_complete,
+		EXISTS(SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id
+			WHERE d.task_id = tasks.id AND b.status_end = 'START') AS blocked,
+		ARRAY(SELECT d.blocked_by_id FROM task_dependencies d WHERE d.task_id = tasks.id ORDER BY 1) AS blocked_by,
+		import_uid, series_id, recurrence_id`
+	priorityRank = "CASE priority WHEN 'urgent' THEN 3 WHEN 'high' THEN 2 WHEN 'normal' THEN 1 ELSE 0 END"
+)
+
+var taskOrders = map[task_manager.TaskSort]string{
+	task_manager.SortDefault:   "overdue DESC, " + priorityRank + " DESC, due_at ASC NULLS LAST, start_time_at ASC, id",
+	task_manager.SortPriority:  priorityRank + " DESC, start_time_at ASC, id",
+	task_manager.SortDueAt:     "due_at ASC NULLS LAST, " + priorityRank + " DESC, id",
+	task_manager.SortStartTime: "start_time_at ASC, id",
+	task_manager.SortCreatedAt: "created_at ASC, id",
+}
+
+const listOrder = "position ASC, id"
+
+// nextPosition is the position after the last task of list $2 of user $1.
+const nextPosition = "(SELECT COALESCE(max(position), 0) + 1 FROM tasks WHERE telegram_id = $1 AND list_id IS NOT DISTINCT FROM $2)"
+
+// nextInboxPosition is the position after the last task of user $1 outside
+// of lists.
+const nextInboxPosition = "(SELECT COALESCE(max(position), 0) + 1 FROM tasks WHERE telegram_id = $1 AND list_id IS NULL)"
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrVersionMismatch = errors.New("version mismatch")
 )
 
 type TaskPostgres struct {
-	db *sqlx.DB
+	db     DBTX
+	logger *slog.Logger
 }
 
-func NewTaskPostgres(db *sqlx.DB) *TaskPostgres {
-	return &TaskPostgres{db: db}
+func NewTaskPostgres(db DBTX, logger *slog.Logger) *TaskPostgres {
+	return &TaskPostgres{db: db, logger: logger}
 }
 
-func (r *TaskPostgres) Create(task task_manager.CreateTaskInput, status task_manager.StatusEnd) (id int, err error) {
-	query := fmt.Sprintf("INSERT INTO %s (text, telegram_id, status_end, start_time_at) VALUES ($1, $2, $3, $4) RETURNING id", tasksTable)
-	row := r.db.QueryRow(query, task.Text, task.TelegramId, status, task.StartTime)
-	err = row.Scan(&id)
+func (r *TaskPostgres) Create(ctx context.Context, task task_manager.CreateTaskInput, status task_manager.StatusEnd) (id int, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, list_id, text, status_end, start_time_at, priority, due_at,
+		checklist_auto_complete, import_uid, series_id, recurrence_id, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $10::integer IS NOT NULL THEN $5::timestamp END, %s) RETURNING id`,
+		tasksTable, nextPosition)
+	if err = lock(ctx, r.db, lockTaskPositions, task.TelegramId); err != nil {
+		return 0, err
+	}
+	ctx, span := startSpan(ctx, tasksTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx, query, task.TelegramId, task.ListId, task.Text, status, task.StartTime, task.Priority, task.DueAt,
+		task.ChecklistAutoComplete, task.ImportUid, task.SeriesId)
+	if err = row.Scan(&id); isUniqueViolation(err) {
+		err = ErrAlreadyExists
+	}
 	return
 }
 
-func (r *TaskPostgres) GetAll(telegramId int) ([]task_manager.Task, error) {
-	var tasks []task_manager.Task
+func (r *TaskPostgres) GetAll(ctx context.Context, telegramId int, filter task_manager.TaskFilter) (tasks []task_manager.Task, err error) {
+	order, ok := taskOrders[filter.Sort]
+	if !ok || filter.ListId != 0 && filter.Sort == task_manager.SortDefault {
+		order = listOrder
+	}
+	where, args := "telegram_id = $1", []any{telegramId}
+	if filter.ListId != 0 {
+		args = append(args, filter.ListId)
+		where += fmt.Sprintf(" AND list_id = $%d", len(args))
+	}
+	if !filter.Archived {
+		where += " AND archived_at IS NULL"
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.StringArray(filter.Tags))
+		tagged := fmt.Sprintf(`SELECT count(DISTINCT g.name) FROM %s tt JOIN %s g ON g.id = tt.tag_id
+			WHERE tt.task_id = tasks.id AND g.name = ANY($%d::text[])`, tasksTagsTable, tagsTable, len(args))
+		if filter.TagMode == task_manager.TagModeOr {
+			where += fmt.Sprintf(" AND (%s) > 0", tagged)
+		} else {
+			where += fmt.Sprintf(" AND (%s) = cardinality($%d::text[])", tagged, len(args))
+		}
+	}
 
-	query := fmt.Sprintf("SELECT id, text, status_end, created_at, updated_at, end_task_at, telegram_id, start_time_at FROM %s WHERE telegram_id = $1", tasksTable)
-	err := r.db.Select(&tasks, query, telegramId)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s", taskColumns, tasksTable, where, order)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
 
+	err = r.db.SelectContext(ctx, &tasks, query, args...)
 	return tasks, err
 }
 
-func (r *TaskPostgres) GetById(taskId int) (task_manager.Task, error) {
-	var task task_manager.Task
+func (r *TaskPostgres) GetById(ctx context.Context, taskId int) (task task_manager.Task, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", taskColumns, tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
 
-	query := fmt.Sprintf("SELECT id, text, status_end, created_at, updated_at, end_task_at, telegram_id, start_time_at FROM %s WHERE id = $1", tasksTable)
-	err := r.db.Get(&task, query, taskId)
+	err = r.db.GetContext(ctx, &task, query, taskId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return task, err
+}
+
+// GetByIds returns the tasks with the given ids ordered by id; missing ids
+// are skipped.
+func (r *TaskPostgres) GetByIds(ctx context.Context, taskIds []int) (tasks []task_manager.Task, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ANY($1::int[]) ORDER BY id", taskColumns, tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &tasks, query, intArray(taskIds))
+	return tasks, err
+}
+
+// Update changes the given fields if the task is still at version. A zero
+// version skips the check.
+func (r *TaskPostgres) Update(ctx context.Context, taskId int, input task_manager.UpdateTaskInput, version int) (task task_manager.Task, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET text = COALESCE($1, text), start_time_at = COALESCE($2, start_time_at),
+		priority = COALESCE($3, priority), due_at = CASE WHEN $8 THEN NULL ELSE COALESCE($4, due_at) END,
+		checklist_auto_complete = COALESCE($7, checklist_auto_complete)
+		WHERE id = $5 AND ($6 = 0 OR version = $6) RETURNING %s`, tasksTable, taskColumns)
+	ctx, span := startSpan(ctx, tasksTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
 
+	err = r.db.GetContext(ctx, &task, query, input.Text, input.StartTime, input.Priority, input.DueAt, taskId, version,
+		input.ChecklistAutoComplete, input.ClearDueAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.missingOrConflict(ctx, taskId)
+	}
 	return task, err
 }
 
-func (r *TaskPostgres) Delete(taskId int) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tasksTable)
-	_, err := r.db.Exec(query, taskId)
+func (r *TaskPostgres) Complete(ctx context.Context, taskId int, version int) (task task_manager.Task, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET status_end = $1
+		WHERE id = $2 AND ($3 = 0 OR version = $3) RETURNING %s`, tasksTable, taskColumns)
+	ctx, span := startSpan(ctx, tasksTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &task, query, task_manager.End, taskId, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.missingOrConflict(ctx, taskId)
+	}
+	return task, err
+}
 
-	return err
+// Move puts the task into list listId, or out of lists when it is nil, at
+// position or after the last task of the list.
+func (r *TaskPostgres) Move(ctx context.Context, taskId int, listId, position *int, version int) (task task_manager.Task, err error) {
+	current, err := r.GetById(ctx, taskId)
+	if err != nil {
+		return task, err
+	}
+	if err = lock(ctx, r.db, lockTaskPositions, strconv.Itoa(current.TelegramId)); err != nil {
+		return task, err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET list_id = $2, position = COALESCE($3, %s),
+		archived_at = (SELECT archived_at FROM %s WHERE id = $2)
+		WHERE id = $4 AND telegram_id = $1 AND ($5 = 0 OR version = $5) RETURNING %s`,
+		tasksTable, nextPosition, listsTable, taskColumns)
+	ctx, span := startSpan(ctx, tasksTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &task, query, current.TelegramId, listId, position, taskId, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.missingOrConflict(ctx, taskId)
+	}
+	return task, err
+}
+
+// Export calls fn with every task of the user, including completed and
+// archived ones, without loading them all into memory.
+func (r *TaskPostgres) Export(ctx context.Context, telegramId int, fn func(task_manager.Task) error) (err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE telegram_id = $1 ORDER BY id", taskColumns, tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryxContext(ctx, query, telegramId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task task_manager.Task
+		if err = rows.StructScan(&task); err != nil {
+			return err
+		}
+		if err = fn(task); err != nil {
+			return err
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// Import creates a task with its tags for every record. Run it in a
+// transaction to import either all records or none.
+func (r *TaskPostgres) Import(ctx context.Context, telegramId int, records []task_manager.TaskRecord) (ids []int, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, text, status_end, start_time_at, priority, due_at, end_task_at, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, %s) RETURNING id`, tasksTable, nextInboxPosition)
+	if err = lock(ctx, r.db, lockTaskPositions, strconv.Itoa(telegramId)); err != nil {
+		return nil, err
+	}
+	ids = make([]int, 0, len(records))
+	for _, record := range records {
+		created, err := queryIds(ctx, r.db, tasksTable, "INSERT", query, telegramId, record.Text, record.Status,
+			record.StartTime, record.Priority, record.DueAt, record.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(record.Tags) > 0 {
+			if err = setTaskTags(ctx, r.db, telegramId, created[0], record.Tags); err != nil {
+				return nil, err
+			}
+		}
+		ids = append(ids, created[0])
+	}
+	return ids, nil
+}
+
+func (r *TaskPostgres) Delete(ctx context.Context, taskId int, version int) (err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND ($2 = 0 OR version = $2)", tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "DELETE", query)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, query, taskId, version)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		r.logger.DebugContext(ctx, "task to delete not found", "task_id", taskId, "version", version)
+		return r.missingOrConflict(ctx, taskId)
+	}
+	return nil
+}
+
+// missingOrConflict tells why a conditional statement matched no rows.
+func (r *TaskPostgres) missingOrConflict(ctx context.Context, taskId int) error {
+	if _, err := r.GetById(ctx, taskId); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+func (r *TaskPostgres) CountActive(ctx context.Context) (pending int, overdue int, err error) {
+	query := fmt.Sprintf(`SELECT
+		count(*) FILTER (WHERE start_time_at > now()),
+		count(*) FILTER (WHERE start_time_at <= now())
+		FROM %s WHERE status_end = $1`, tasksTable)
+	ctx, span := startSpan(ctx, tasksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx, query, task_manager.Start).Scan(&pending, &overdue)
+	return
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"task_manager"
+	"task_manager/pkg/tracing"
+)
+
+const templateColumns = "id, telegram_id, name, text, priority, tags, times, reminder_offsets, recurrence, created_at, updated_at"
+
+type TemplatePostgres struct {
+	db DBTX
+}
+
+func NewTemplatePostgres(db DBTX) *TemplatePostgres {
+	return &TemplatePostgres{db: db}
+}
+
+func (r *TemplatePostgres) GetAll(ctx context.Context, telegramId int) (templates []task_manager.TaskTemplate, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE telegram_id = $1 ORDER BY name", templateColumns, taskTemplatesTable)
+	ctx, span := startSpan(ctx, taskTemplatesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &templates, query, telegramId)
+	return templates, err
+}
+
+func (r *TemplatePostgres) GetById(ctx context.Context, templateId int) (template task_manager.TaskTemplate, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", templateColumns, taskTemplatesTable)
+	ctx, span := startSpan(ctx, taskTemplatesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &template, query, templateId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return template, err
+}
+
+func (r *TemplatePostgres) Create(ctx context.Context, telegramId int, input task_manager.TaskTemplateInput) (template task_manager.TaskTemplate, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, name, text, priority, tags, times, reminder_offsets, recurrence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING %s`, taskTemplatesTable, templateColumns)
+	ctx, span := startSpan(ctx, taskTemplatesTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &template, query, telegramId, input.Name, input.Text, input.Priority,
+		pq.StringArray(input.Tags), pq.StringArray(input.Times), pq.Int64Array(input.ReminderOffsets), input.Recurrence)
+	if isUniqueViolation(err) {
+		err = ErrAlreadyExists
+	}
+	return template, err
+}
+
+// Update replaces all fields of the template.
+func (r *TemplatePostgres) Update(ctx context.Context, templateId int, input task_manager.TaskTemplateInput) (template task_manager.TaskTemplate, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET name = $1, text = $2, priority = $3, tags = $4, times = $5,
+		reminder_offsets = $6, recurrence = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8 RETURNING %s`, taskTemplatesTable, templateColumns)
+	ctx, span := startSpan(ctx, taskTemplatesTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &template, query, input.Name, input.Text, input.Priority, pq.StringArray(input.Tags),
+		pq.StringArray(input.Times), pq.Int64Array(input.ReminderOffsets), input.Recurrence, templateId)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = ErrNotFound
+	case isUniqueViolation(err):
+		err = ErrAlreadyExists
+	}
+	return template, err
+}
+
+func (r *TemplatePostgres) Delete(ctx context.Context, templateId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", taskTemplatesTable)
+	res, err := execContext(ctx, r.db, taskTemplatesTable, "DELETE", query, templateId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
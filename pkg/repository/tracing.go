@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"task_manager/pkg/tracing"
+)
+
+// startSpan opens a client span describing a single SQL statement.
+func startSpan(ctx context.Context, table, operation, query string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBSQLTable(table),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// execContext runs a single statement in its own span.
+func execContext(ctx context.Context, db sqlx.ExecerContext, table, operation, query string, args ...any) (res sql.Result, err error) {
+	ctx, span := startSpan(ctx, table, operation, query)
+	defer func() { tracing.End(span, err) }()
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// queryIds runs a traced statement returning the ids of affected rows.
+func queryIds(ctx context.Context, db sqlx.QueryerContext, table, operation, query string, args ...any) (ids []int, err error) {
+	ctx, span := startSpan(ctx, table, operation, query)
+	defer func() { tracing.End(span, err) }()
+
+	err = sqlx.SelectContext(ctx, db, &ids, query, args...)
+	return ids, err
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"strconv"
+	"task_manager/pkg/tracing"
+)
+
+// maxTxAttempts limits runs of a unit of work failing with serialization
+// failures or deadlocks.
+const maxTxAttempts = 3
+
+// DBTX is the part of *sqlx.DB and *sqlx.Tx the repositories use, so the
+// same repository runs on the pool or inside a transaction.
+type DBTX interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type Transactor interface {
+	// Do calls fn with repositories bound to one transaction, committing it
+	// when fn returns nil and rolling it back when fn fails or panics. fn is
+	// run again on serialization failures and deadlocks, so it must not have
+	// effects outside the transaction. Nested calls join the outer one.
+	Do(ctx context.Context, fn func(repos *Repository) error) error
+	// DoSerializable is Do at the serializable isolation level, for units of
+	// work that decide on rows they read. A nested call joins the outer
+	// transaction at its level.
+	DoSerializable(ctx context.Context, fn func(repos *Repository) error) error
+}
+
+func (r *Repository) Do(ctx context.Context, fn func(repos *Repository) error) error {
+	return r.run(ctx, nil, fn)
+}
+
+func (r *Repository) DoSerializable(ctx context.Context, fn func(repos *Repository) error) error {
+	return r.run(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, fn)
+}
+
+// Lock takes an advisory lock on id within scope until the end of the
+// transaction, so units of work checking and changing the same rows run
+// one after another. Outside of Do the lock is released right away.
+func (r *Repository) Lock(ctx context.Context, scope string, id int) (err error) {
+	return lock(ctx, r.db, scope, strconv.Itoa(id))
+}
+
+// lock is Repository.Lock for repositories, keyed by id within scope.
+func lock(ctx context.Context, db DBTX, scope, id string) (err error) {
+	query := "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))"
+	ctx, span := startSpan(ctx, scope, "LOCK", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = db.ExecContext(ctx, query, scope+":"+id)
+	return err
+}
+
+func (r *Repository) run(ctx context.Context, opts *sql.TxOptions, fn func(repos *Repository) error) (err error) {
+	db, ok := r.db.(*sqlx.DB)
+	if !ok {
+		return fn(r)
+	}
+	for attempt := 1; ; attempt++ {
+		err = r.do(ctx, db, opts, fn)
+		if attempt == maxTxAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		r.logger.WarnContext(ctx, "retrying transaction", "attempt", attempt, "error", err)
+	}
+}
+
+func (r *Repository) do(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(repos *Repository) error) (err error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+				r.logger.ErrorContext(ctx, "failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	if err = fn(newRepository(tx, r.metrics, r.logger)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
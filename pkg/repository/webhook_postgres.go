@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"task_manager"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const (
+	webhookColumns  = "id, telegram_id, url, secret, event_types, created_at"
+	deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, response_code, last_error,
+		next_attempt_at, created_at, delivered_at`
+)
+
+type WebhookPostgres struct {
+	db DBTX
+}
+
+func NewWebhookPostgres(db DBTX) *WebhookPostgres {
+	return &WebhookPostgres{db: db}
+}
+
+func (r *WebhookPostgres) GetAll(ctx context.Context, telegramId int) (webhooks []task_manager.Webhook, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE telegram_id = $1 ORDER BY id", webhookColumns, webhooksTable)
+	ctx, span := startSpan(ctx, webhooksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &webhooks, query, telegramId)
+	return webhooks, err
+}
+
+func (r *WebhookPostgres) GetById(ctx context.Context, webhookId int) (webhook task_manager.Webhook, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", webhookColumns, webhooksTable)
+	ctx, span := startSpan(ctx, webhooksTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &webhook, query, webhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return webhook, err
+}
+
+func (r *WebhookPostgres) Create(ctx context.Context, telegramId int, input task_manager.WebhookInput) (webhook task_manager.Webhook, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (telegram_id, url, secret, event_types) VALUES ($1, $2, $3, $4)
+		RETURNING %s`, webhooksTable, webhookColumns)
+	ctx, span := startSpan(ctx, webhooksTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &webhook, query, telegramId, input.Url, input.Secret, eventTypes(input.EventTypes))
+	return webhook, err
+}
+
+// Update keeps the secret when input.Secret is empty.
+func (r *WebhookPostgres) Update(ctx context.Context, webhookId int, input task_manager.WebhookInput) (webhook task_manager.Webhook, err error) {
+	query := fmt.Sprintf(`UPDATE %s SET url = $1, secret = COALESCE(NULLIF($2, ''), secret), event_types = $3
+		WHERE id = $4 RETURNING %s`, webhooksTable, webhookColumns)
+	ctx, span := startSpan(ctx, webhooksTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &webhook, query, input.Url, input.Secret, eventTypes(input.EventTypes), webhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return webhook, err
+}
+
+func (r *WebhookPostgres) Delete(ctx context.Context, webhookId int) (err error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", webhooksTable)
+	res, err := execContext(ctx, r.db, webhooksTable, "DELETE", query, webhookId)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Enqueue adds a pending delivery of the event with body to every webhook
+// of its user subscribed to its type. Enqueueing an event again adds no
+// deliveries.
+func (r *WebhookPostgres) Enqueue(ctx context.Context, event task_manager.Event, body []byte) (n int64, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM %s WHERE telegram_id = $4 AND $2 = ANY(event_types)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`, webhookDeliveriesTable, webhooksTable)
+	res, err := execContext(ctx, r.db, webhookDeliveriesTable, "INSERT", query, event.Id, event.Type, body, event.TelegramId)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CreateDelivery adds a delivery not tied to an event, counted as a first
+// attempt in progress so the delivery worker leaves it alone for lease.
+func (r *WebhookPostgres) CreateDelivery(ctx context.Context, webhookId int, eventType task_manager.EventType, body []byte, lease time.Duration) (delivery task_manager.WebhookDelivery, err error) {
+	query := fmt.Sprintf(`INSERT INTO %s (webhook_id, event_type, payload, attempts, next_attempt_at)
+		VALUES ($1, $2, $3, 1, CURRENT_TIMESTAMP + $4 * interval '1 second') RETURNING %s`, webhookDeliveriesTable, deliveryColumns)
+	ctx, span := startSpan(ctx, webhookDeliveriesTable, "INSERT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.GetContext(ctx, &delivery, query, webhookId, eventType, body, lease.Seconds())
+	return delivery, err
+}
+
+func (r *WebhookPostgres) GetDeliveries(ctx context.Context, webhookId int, limit int) (deliveries []task_manager.WebhookDelivery, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2", deliveryColumns, webhookDeliveriesTable)
+	ctx, span := startSpan(ctx, webhookDeliveriesTable, "SELECT", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &deliveries, query, webhookId, limit)
+	return deliveries, err
+}
+
+// ClaimDeliveries takes up to limit pending deliveries that are due,
+// counting the attempt and hiding them from other workers for lease.
+func (r *WebhookPostgres) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []task_manager.PendingDelivery, err error) {
+	query := fmt.Sprintf(`WITH due AS (
+			SELECT id FROM %[1]s WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE %[1]s d SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $3 * interval '1 second'
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT d.*, w.url, w.secret FROM claimed d JOIN %[2]s w ON w.id = d.webhook_id ORDER BY d.id`,
+		webhookDeliveriesTable, webhooksTable)
+	ctx, span := startSpan(ctx, webhookDeliveriesTable, "UPDATE", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.SelectContext(ctx, &deliveries, query, task_manager.DeliveryPending, limit, lease.Seconds())
+	return deliveries, err
+}
+
+func (r *WebhookPostgres) MarkDelivered(ctx context.Context, deliveryId int64, responseCode int) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $2, response_code = $3, last_error = NULL, delivered_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, webhookDeliveriesTable)
+	_, err := execContext(ctx, r.db, webhookDeliveriesTable, "UPDATE", query, deliveryId, task_manager.DeliveryDelivered, responseCode)
+	return err
+}
+
+// MarkFailed records a failed attempt and schedules the next one after delay.
+func (r *WebhookPostgres) MarkFailed(ctx context.Context, deliveryId int64, responseCode *int, reason string, delay time.Duration) error {
+	query := fmt.Sprintf(`UPDATE %s SET response_code = $2, last_error = $3,
+		next_attempt_at = CURRENT_TIMESTAMP + $4 * interval '1 second' WHERE id = $1`, webhookDeliveriesTable)
+	_, err := execContext(ctx, r.db, webhookDeliveriesTable, "UPDATE", query, deliveryId, responseCode, reason, delay.Seconds())
+	return err
+}
+
+// MarkDead gives up on the delivery after a failed attempt.
+func (r *WebhookPostgres) MarkDead(ctx context.Context, deliveryId int64, responseCode *int, reason string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $2, response_code = $3, last_error = $4 WHERE id = $1", webhookDeliveriesTable)
+	_, err := execContext(ctx, r.db, webhookDeliveriesTable, "UPDATE", query, deliveryId, task_manager.DeliveryDead, responseCode, reason)
+	return err
+}
+
+func eventTypes(types []task_manager.EventType) pq.StringArray {
+	names := make(pq.StringArray, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return names
+}
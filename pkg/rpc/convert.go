@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"task_manager"
+	"task_manager/pkg/rpc/taskpb"
+	"time"
+)
+
+var priorities = map[task_manager.Priority]taskpb.Priority{
+	task_manager.PriorityLow:    taskpb.Priority_PRIORITY_LOW,
+	task_manager.PriorityNormal: taskpb.Priority_PRIORITY_NORMAL,
+	task_manager.PriorityHigh:   taskpb.Priority_PRIORITY_HIGH,
+	task_manager.PriorityUrgent: taskpb.Priority_PRIORITY_URGENT,
+}
+
+var sorts = map[taskpb.TaskSort]task_manager.TaskSort{
+	taskpb.TaskSort_TASK_SORT_DEFAULT:    task_manager.SortDefault,
+	taskpb.TaskSort_TASK_SORT_PRIORITY:   task_manager.SortPriority,
+	taskpb.TaskSort_TASK_SORT_DUE_AT:     task_manager.SortDueAt,
+	taskpb.TaskSort_TASK_SORT_START_TIME: task_manager.SortStartTime,
+	taskpb.TaskSort_TASK_SORT_CREATED_AT: task_manager.SortCreatedAt,
+}
+
+func toTask(task task_manager.Task) *taskpb.Task {
+	result := &taskpb.Task{
+		Id:                    int64(task.Id),
+		TelegramId:            int64(task.TelegramId),
+		Text:                  task.Text,
+		StartTime:             toTimestamp(&task.StartTimeAt),
+		Status:                taskpb.Status_STATUS_START,
+		CompletedAt:           toTimestamp(task.EndTask),
+		Version:               int32(task.Version),
+		Priority:              priorities[task.Priority],
+		DueAt:                 toTimestamp(task.DueAt),
+		Overdue:               task.Overdue,
+		Tags:                  task.Tags,
+		Position:              int32(task.Position),
+		ChecklistAutoComplete: task.ChecklistAutoComplete,
+		Blocked:               task.Blocked,
+		BlockedBy:             task.BlockedBy,
+		ArchivedAt:            toTimestamp(task.ArchivedAt),
+		CreatedAt:             toTimestamp(&task.CreatedAt),
+		UpdatedAt:             toTimestamp(&task.UpdatedAt),
+	}
+	if task.StatusEnd == task_manager.End {
+		result.Status = taskpb.Status_STATUS_END
+	}
+	if task.ListId != nil {
+		listId := int64(*task.ListId)
+		result.ListId = &listId
+	}
+	return result
+}
+
+// fromPriority returns the empty priority, the service default, for
+// PRIORITY_UNSPECIFIED and an invalid one for unknown values.
+func fromPriority(priority taskpb.Priority) task_manager.Priority {
+	if priority == taskpb.Priority_PRIORITY_UNSPECIFIED {
+		return ""
+	}
+	for value, p := range priorities {
+		if p == priority {
+			return value
+		}
+	}
+	return task_manager.Priority(priority.String())
+}
+
+// fromSort returns an invalid sort, rejected by the service, for unknown
+// values.
+func fromSort(sort taskpb.TaskSort) task_manager.TaskSort {
+	if value, ok := sorts[sort]; ok {
+		return value
+	}
+	return task_manager.TaskSort(sort.String())
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromTimestamp(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.AsTime()
+	return &value
+}
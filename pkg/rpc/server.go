@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"task_manager"
+	"task_manager/pkg/rpc/taskpb"
+	"task_manager/pkg/service"
+	"time"
+)
+
+// defaultAddr keeps the API, which does not authenticate callers, off
+// other hosts unless an address is configured.
+const defaultAddr = "localhost:9090"
+
+type Config struct {
+	// Addr is the address to listen on, localhost:9090 by default.
+	Addr string
+	// Reflection registers the reflection service for tools like grpcurl.
+	Reflection bool
+}
+
+// Server serves the gRPC API.
+type Server struct {
+	server *grpc.Server
+	addr   string
+	logger *slog.Logger
+}
+
+func NewServer(config Config, services *service.Service, logger *slog.Logger) *Server {
+	if config.Addr == "" {
+		config.Addr = defaultAddr
+	}
+	s := &Server{addr: config.Addr, logger: logger}
+	s.server = grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(s.logUnary),
+		grpc.ChainStreamInterceptor(s.logStream),
+	)
+	taskpb.RegisterTaskServiceServer(s.server, newTaskServer(services.TaskManagerTask, services.Stream))
+	if config.Reflection {
+		reflection.Register(s.server)
+	}
+	return s
+}
+
+// Run serves until ctx is cancelled and then stops gracefully, cancelling
+// the calls still running at the shutdown deadline; it is a worker of
+// task_manager.Server.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.addr, err)
+	}
+	s.logger.Info("grpc server listening", "addr", listener.Addr().String())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.server.Serve(listener)
+	}()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-task_manager.ShutdownContext(ctx).Done():
+		s.server.Stop()
+	}
+	return ctx.Err()
+}
+
+func (s *Server) logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.log(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *Server) logStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.log(stream.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// log records a call like the access log of the REST API.
+func (s *Server) log(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	s.logger.LogAttrs(ctx, level, "rpc", attrs...)
+}
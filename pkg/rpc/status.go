@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"task_manager/pkg/service"
+)
+
+// errorStatus maps errors returned by services to gRPC statuses, the way
+// the REST API maps them to status codes.
+func errorStatus(err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, service.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrVersionMismatch):
+		code = codes.Aborted
+	case errors.Is(err, service.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	case errors.Is(err, service.ErrListArchived):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrStreamClosed):
+		code = codes.Unavailable
+	case errors.Is(err, service.ErrReplayUnavailable):
+		code = codes.OutOfRange
+	case errors.Is(err, service.ErrNothingToUpdate),
+		errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidSort),
+		errors.Is(err, service.ErrClearDueAt),
+		errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidTagMode),
+		errors.Is(err, service.ErrForeignList),
+		errors.Is(err, service.ErrTooManyTasks),
+		errors.Is(err, service.ErrInvalidTelegramId):
+		code = codes.InvalidArgument
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,251 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"task_manager"
+	"task_manager/pkg/rpc/taskpb"
+	"task_manager/pkg/service"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// taskServer implements the task service on top of the service layer
+// shared with the REST API.
+type taskServer struct {
+	taskpb.UnimplementedTaskServiceServer
+
+	tasks  service.TaskManagerTask
+	stream service.Stream
+}
+
+func newTaskServer(tasks service.TaskManagerTask, stream service.Stream) *taskServer {
+	return &taskServer{tasks: tasks, stream: stream}
+}
+
+func (s *taskServer) CreateTask(ctx context.Context, req *taskpb.CreateTaskRequest) (*taskpb.Task, error) {
+	if req.Text == "" || req.StartTime == nil {
+		return nil, status.Error(codes.InvalidArgument, "text and start_time are required")
+	}
+	input := task_manager.CreateTaskInput{
+		Text:                  req.Text,
+		StartTime:             req.StartTime.AsTime(),
+		TelegramId:            strconv.FormatInt(req.TelegramId, 10),
+		Priority:              fromPriority(req.Priority),
+		DueAt:                 fromTimestamp(req.DueAt),
+		Tags:                  req.Tags,
+		ChecklistAutoComplete: req.ChecklistAutoComplete,
+	}
+	if req.ListId != nil {
+		listId := int(*req.ListId)
+		input.ListId = &listId
+	}
+
+	id, err := s.tasks.Create(ctx, input)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	task, err := s.tasks.GetById(ctx, id)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return toTask(task), nil
+}
+
+func (s *taskServer) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.Task, error) {
+	task, err := s.tasks.GetById(ctx, int(req.Id))
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return toTask(task), nil
+}
+
+// ListTasks pages through the tasks of the user by offset, so tasks
+// created or deleted between calls may shift pages.
+func (s *taskServer) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	offset, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	filter := task_manager.TaskFilter{
+		Sort:     fromSort(req.Sort),
+		Tags:     req.Tags,
+		TagMode:  task_manager.TagModeAnd,
+		ListId:   int(req.ListId),
+		Archived: req.IncludeArchived,
+		// One more task tells whether there is a next page.
+		Limit:  pageSize + 1,
+		Offset: offset,
+	}
+	if req.AnyTag {
+		filter.TagMode = task_manager.TagModeOr
+	}
+
+	tasks, err := s.tasks.GetAll(ctx, int(req.TelegramId), filter)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	resp := &taskpb.ListTasksResponse{}
+	for _, task := range tasks[:min(pageSize, len(tasks))] {
+		resp.Tasks = append(resp.Tasks, toTask(task))
+	}
+	if len(tasks) > pageSize {
+		resp.NextPageToken = encodePageToken(offset + pageSize)
+	}
+	return resp, nil
+}
+
+func (s *taskServer) UpdateTask(ctx context.Context, req *taskpb.UpdateTaskRequest) (*taskpb.Task, error) {
+	input := task_manager.UpdateTaskInput{
+		Text:                  req.Text,
+		StartTime:             fromTimestamp(req.StartTime),
+		DueAt:                 fromTimestamp(req.DueAt),
+		ClearDueAt:            req.ClearDueAt,
+		ChecklistAutoComplete: req.ChecklistAutoComplete,
+	}
+	if req.Priority != taskpb.Priority_PRIORITY_UNSPECIFIED {
+		priority := fromPriority(req.Priority)
+		input.Priority = &priority
+	}
+
+	version, err := expectedVersion(req.Version, req.AnyVersion)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.Update(ctx, int(req.Id), input, version)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return toTask(task), nil
+}
+
+func (s *taskServer) CompleteTask(ctx context.Context, req *taskpb.CompleteTaskRequest) (*taskpb.Task, error) {
+	version, err := expectedVersion(req.Version, req.AnyVersion)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.Complete(ctx, int(req.Id), version)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return toTask(task), nil
+}
+
+func (s *taskServer) DeleteTask(ctx context.Context, req *taskpb.DeleteTaskRequest) (*taskpb.DeleteTaskResponse, error) {
+	version, err := expectedVersion(req.Version, req.AnyVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tasks.Delete(ctx, int(req.Id), version); err != nil {
+		return nil, errorStatus(err)
+	}
+	return &taskpb.DeleteTaskResponse{}, nil
+}
+
+// expectedVersion returns the version a change is based on, zero to skip
+// the check with anyVersion. Like If-Match of the REST API, one of them is
+// required.
+func expectedVersion(version int32, anyVersion bool) (int, error) {
+	if anyVersion {
+		return 0, nil
+	}
+	if version <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "version is required unless any_version is set")
+	}
+	return int(version), nil
+}
+
+// WatchTasks sends the task events of the SSE stream. The stream ends with
+// Unavailable when the client falls behind or the server stops; clients
+// resume with the id of the last event they received.
+func (s *taskServer) WatchTasks(req *taskpb.WatchTasksRequest, stream taskpb.TaskService_WatchTasksServer) error {
+	ctx := stream.Context()
+	sub, err := s.stream.Subscribe(ctx, int(req.TelegramId))
+	if err != nil {
+		return errorStatus(err)
+	}
+	defer sub.Close()
+
+	send := func(event task_manager.Event) error {
+		taskEvent, err := toTaskEvent(event)
+		if err != nil || taskEvent == nil {
+			return err
+		}
+		return stream.Send(taskEvent)
+	}
+	if req.LastEventId > 0 {
+		if err = sub.Replay(ctx, req.LastEventId, send); err != nil {
+			return errorStatus(err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return errorStatus(ctx.Err())
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream closed")
+			}
+			if err = send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toTaskEvent converts task events and skips the others with nil.
+func toTaskEvent(event task_manager.Event) (*taskpb.TaskEvent, error) {
+	taskEvent := &taskpb.TaskEvent{
+		Id:        event.Id,
+		Type:      string(event.Type),
+		CreatedAt: toTimestamp(&event.CreatedAt),
+	}
+	switch event.Type {
+	case task_manager.EventTaskCreated, task_manager.EventTaskUpdated, task_manager.EventTaskCompleted:
+		var task task_manager.Task
+		if err := json.Unmarshal(event.Payload, &task); err != nil {
+			return nil, err
+		}
+		taskEvent.TaskId, taskEvent.Task = int64(task.Id), toTask(task)
+	case task_manager.EventTaskDeleted:
+		var task task_manager.DeletedTask
+		if err := json.Unmarshal(event.Payload, &task); err != nil {
+			return nil, err
+		}
+		taskEvent.TaskId = int64(task.Id)
+	default:
+		return nil, nil
+	}
+	return taskEvent, nil
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return offset, nil
+}
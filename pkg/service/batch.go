@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"slices"
+	"strconv"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+var (
+	ErrInvalidBatchMode      = errors.New("invalid batch mode")
+	ErrInvalidBatchOperation = errors.New("invalid batch operation")
+)
+
+// Batch applies the operations of input for one user in a single
+// transaction. Invalid operations fail without reaching the database, so
+// in atomic mode they prevent the whole batch.
+func (s *TaskService) Batch(ctx context.Context, input task_manager.BatchInput) (result task_manager.BatchResult, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Batch")
+	defer func() { tracing.End(span, err) }()
+
+	telegramId, err := strconv.Atoi(input.TelegramId)
+	if err != nil {
+		return result, ErrInvalidTelegramId
+	}
+	switch input.Mode {
+	case "":
+		input.Mode = task_manager.BatchAtomic
+	case task_manager.BatchAtomic, task_manager.BatchBestEffort:
+	default:
+		return result, ErrInvalidBatchMode
+	}
+	atomic := input.Mode == task_manager.BatchAtomic
+	span.SetAttributes(attribute.Int("telegram.id", telegramId), attribute.String("batch.mode", string(input.Mode)),
+		attribute.Int("batch.operations", len(input.Operations)))
+
+	result = task_manager.BatchResult{Mode: input.Mode, Results: make([]task_manager.BatchItemResult, len(input.Operations))}
+	var valid []task_manager.BatchOperation
+	var indexes []int
+	var texts []string
+	for i, op := range input.Operations {
+		result.Results[i] = task_manager.BatchItemResult{Index: i, Op: op.Op, Status: task_manager.BatchSkipped, TaskIds: []int{}}
+		if err := s.checkBatchOperation(ctx, telegramId, &op); err != nil {
+			result.Results[i].Status = task_manager.BatchFailed
+			result.Results[i].Error = err.Error()
+			continue
+		}
+		if op.Op == task_manager.BatchCreate {
+			texts = append(texts, *op.Task.Text)
+		}
+		valid = append(valid, op)
+		indexes = append(indexes, i)
+	}
+	if atomic && len(valid) < len(input.Operations) {
+		return result, nil
+	}
+	if len(valid) > 0 {
+		var results []task_manager.BatchItemResult
+		err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+			if len(texts) > 0 {
+				if err = s.quota.CheckCreateAll(ctx, repos, telegramId, texts); err != nil {
+					return err
+				}
+			}
+			if results, err = repos.TaskManagerTask.Batch(ctx, telegramId, valid, atomic); err != nil {
+				return err
+			}
+			return publishBatch(ctx, repos, telegramId, results)
+		})
+		if err != nil && !errors.Is(err, repository.ErrBatchFailed) {
+			return result, err
+		}
+		for i, item := range results {
+			item.Index = indexes[i]
+			result.Results[indexes[i]] = item
+		}
+	}
+	result.Committed = !atomic || !slices.ContainsFunc(result.Results, func(item task_manager.BatchItemResult) bool {
+		return item.Status == task_manager.BatchFailed
+	})
+	s.logger.InfoContext(ctx, "batch applied", "telegram_id", telegramId, "mode", input.Mode,
+		"operations", len(input.Operations), "committed", result.Committed)
+	return result, nil
+}
+
+// publishBatch adds events of the tasks changed by the successful
+// operations of a batch.
+func publishBatch(ctx context.Context, repos *repository.Repository, telegramId int, results []task_manager.BatchItemResult) error {
+	for _, item := range results {
+		if item.Status != task_manager.BatchOk {
+			continue
+		}
+		for _, id := range item.TaskIds {
+			var err error
+			switch item.Op {
+			case task_manager.BatchCreate:
+				err = publishTask(ctx, repos, task_manager.EventTaskCreated, id)
+			case task_manager.BatchUpdate:
+				err = publishTask(ctx, repos, task_manager.EventTaskUpdated, id)
+			case task_manager.BatchComplete:
+				err = publishTask(ctx, repos, task_manager.EventTaskCompleted, id)
+			case task_manager.BatchDelete:
+				err = publish(ctx, repos, task_manager.EventTaskDeleted, telegramId, task_manager.DeletedTask{Id: id})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkBatchOperation validates op and normalizes it the way the single
+// task endpoints do: default priority and tags parsed from the text.
+func (s *TaskService) checkBatchOperation(ctx context.Context, telegramId int, op *task_manager.BatchOperation) error {
+	task := op.Task
+	if task != nil {
+		if task.Priority != nil && !task.Priority.Valid() {
+			return ErrInvalidPriority
+		}
+		if task.Text != nil {
+			text := strings.TrimSpace(*task.Text)
+			task.Text = &text
+		}
+		task.StartTime, task.DueAt = utc(task.StartTime), utc(task.DueAt)
+	}
+
+	switch op.Op {
+	case task_manager.BatchCreate:
+		if op.Id != 0 || op.Filter != nil {
+			return batchOperationError("create takes no id or filter")
+		}
+		if task == nil || task.Text == nil || *task.Text == "" || task.StartTime == nil {
+			return batchOperationError("create requires task text and start_time")
+		}
+		if task.ClearDueAt {
+			return batchOperationError("clear_due_at can only be set on update")
+		}
+		if task.Priority == nil {
+			priority := task_manager.PriorityNormal
+			task.Priority = &priority
+		}
+		tags := parseHashtags(*task.Text)
+		for _, tag := range task.Tags {
+			name, err := normalizeTag(tag)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(tags, name) {
+				tags = append(tags, name)
+			}
+		}
+		task.Tags = tags
+		if task.ListId != nil {
+			return s.checkList(ctx, telegramId, *task.ListId)
+		}
+		return nil
+	case task_manager.BatchUpdate:
+		if task == nil && op.Shift == 0 {
+			return batchOperationError("update requires task changes or shift")
+		}
+		if task != nil {
+			if task.ListId != nil || task.Tags != nil {
+				return batchOperationError("list_id and tags can only be set on create")
+			}
+			if task.Text != nil && *task.Text == "" {
+				return batchOperationError("text must not be empty")
+			}
+			if task.ClearDueAt && task.DueAt != nil {
+				return batchOperationError("due_at cannot be set and cleared at once")
+			}
+			if task.Text != nil {
+				if err := s.quota.CheckText(ctx, telegramId, *task.Text); err != nil {
+					return err
+				}
+				task.Tags = parseHashtags(*task.Text)
+			}
+		}
+	case task_manager.BatchComplete, task_manager.BatchDelete:
+	default:
+		return batchOperationError("unknown op " + strconv.Quote(string(op.Op)))
+	}
+
+	if (op.Id == 0) == (op.Filter == nil) {
+		return batchOperationError("either id or filter is required")
+	}
+	if filter := op.Filter; filter != nil {
+		switch filter.Status {
+		case "", task_manager.Start, task_manager.End:
+		default:
+			return batchOperationError("invalid filter status")
+		}
+		filter.StartFrom, filter.StartTo = utc(filter.StartFrom), utc(filter.StartTo)
+		tags, err := normalizeTags(filter.Tags)
+		if err != nil {
+			return err
+		}
+		filter.Tags = tags
+	}
+	return nil
+}
+
+func batchOperationError(message string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidBatchOperation, message)
+}
+
+// utc converts t to UTC, since timestamps are stored without time zone.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"strconv"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/ical"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const feedTokenBytes = 32
+
+var (
+	ErrInvalidFeedToken = errors.New("invalid calendar feed token")
+	ErrInvalidCalendar  = errors.New("file is not an iCalendar object")
+)
+
+// CalendarService guards the calendar feeds of users with secret tokens,
+// so calendar apps can subscribe without an Authorization header. Only
+// hashes of the tokens are stored.
+type CalendarService struct {
+	repo   repository.Calendar
+	tasks  TaskManagerTask
+	logger *slog.Logger
+}
+
+func NewCalendarService(repo repository.Calendar, tasks TaskManagerTask, logger *slog.Logger) *CalendarService {
+	return &CalendarService{repo: repo, tasks: tasks, logger: logger}
+}
+
+// RotateToken issues a new feed token of the user, revoking the previous one.
+func (s *CalendarService) RotateToken(ctx context.Context, telegramId int) (token string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CalendarService.RotateToken")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	secret := make([]byte, feedTokenBytes)
+	if _, err = rand.Read(secret); err != nil {
+		return "", err
+	}
+	token = hex.EncodeToString(secret)
+	if err = s.repo.SetTokenHash(ctx, telegramId, hashFeedToken(token)); err != nil {
+		return "", err
+	}
+	s.logger.InfoContext(ctx, "calendar feed token rotated", "telegram_id", telegramId)
+	return token, nil
+}
+
+func (s *CalendarService) RevokeToken(ctx context.Context, telegramId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CalendarService.RevokeToken")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	return s.repo.DeleteToken(ctx, telegramId)
+}
+
+// Feed returns the tasks of the user for the calendar feed and the series
+// of those created from templates if token is valid.
+func (s *CalendarService) Feed(ctx context.Context, telegramId int, token string) (feed task_manager.CalendarFeed, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CalendarService.Feed")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	hash, err := s.repo.GetTokenHash(ctx, telegramId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return feed, ErrInvalidFeedToken
+	}
+	if err != nil {
+		return feed, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashFeedToken(token))) != 1 {
+		return feed, ErrInvalidFeedToken
+	}
+	if feed.Tasks, err = s.tasks.GetAll(ctx, telegramId, task_manager.TaskFilter{Sort: task_manager.SortStartTime}); err != nil {
+		return feed, err
+	}
+	series, err := s.repo.GetSeries(ctx, telegramId)
+	if err != nil {
+		return feed, err
+	}
+	for _, item := range series {
+		rec, err := parseRecurrence(item.Recurrence)
+		if err != nil {
+			s.logger.WarnContext(ctx, "invalid task series recurrence", "series_id", item.Id, "error", err)
+			continue
+		}
+		item.Occurrences = rec.dates(item.StartTimeAt)
+		feed.Series = append(feed.Series, item)
+	}
+	return feed, nil
+}
+
+// Import creates tasks from the events and to-dos of calendar. Components
+// imported before, judging by their UID, and cancelled or completed ones
+// are skipped. TZIDs are resolved with the VTIMEZONE components of calendar
+// and floating times are taken in loc.
+func (s *CalendarService) Import(ctx context.Context, telegramId int, calendar *ical.Component, loc *time.Location) (report task_manager.CalendarImportReport, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CalendarService.Import")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	if calendar.Name != "VCALENDAR" {
+		return report, ErrInvalidCalendar
+	}
+	report.Items = make([]task_manager.CalendarImportItem, 0)
+	zones := ical.NewTimeZones(calendar)
+	for _, component := range calendar.Components {
+		if component.Name != "VEVENT" && component.Name != "VTODO" {
+			continue
+		}
+		report.Add(s.importComponent(ctx, telegramId, component, loc, zones))
+	}
+	span.SetAttributes(attribute.Int("import.created", report.Created), attribute.Int("import.failed", report.Failed))
+	s.logger.InfoContext(ctx, "calendar imported", "telegram_id", telegramId,
+		"created", report.Created, "skipped", report.Skipped, "failed", report.Failed)
+	return report, nil
+}
+
+func (s *CalendarService) importComponent(ctx context.Context, telegramId int, component *ical.Component, loc *time.Location, zones *ical.TimeZones) task_manager.CalendarImportItem {
+	uid, _ := component.Get("UID")
+	summary, _ := component.Get("SUMMARY")
+	item := task_manager.CalendarImportItem{Uid: uid.Text(), Summary: summary.Text()}
+	fail := func(reason string) task_manager.CalendarImportItem {
+		item.Status, item.Reason = task_manager.ImportFailed, reason
+		return item
+	}
+
+	if status, ok := component.Get("STATUS"); ok && (status.Value == "CANCELLED" || status.Value == "COMPLETED") {
+		item.Status, item.Reason = task_manager.ImportSkipped, strings.ToLower(status.Value)
+		return item
+	}
+	if item.Uid == "" {
+		return fail("missing UID")
+	}
+	if strings.TrimSpace(item.Summary) == "" {
+		return fail("missing SUMMARY")
+	}
+
+	input := task_manager.CreateTaskInput{
+		Text:       item.Summary,
+		TelegramId: strconv.Itoa(telegramId),
+		Priority:   importPriority(component),
+		ImportUid:  &item.Uid,
+	}
+	start, ok := component.Get("DTSTART")
+	due, hasDue := component.Get("DUE")
+	if !ok && !hasDue {
+		return fail("missing DTSTART")
+	}
+	if !ok {
+		start = due
+	}
+	// Task times are stored in UTC.
+	startTime, err := start.Time(loc, zones)
+	if err != nil {
+		return fail("invalid DTSTART: " + err.Error())
+	}
+	input.StartTime = startTime.UTC()
+	if hasDue {
+		dueAt, err := due.Time(loc, zones)
+		if err != nil {
+			return fail("invalid DUE: " + err.Error())
+		}
+		dueAt = dueAt.UTC()
+		input.DueAt = &dueAt
+	}
+	if categories, ok := component.Get("CATEGORIES"); ok {
+		for _, category := range categories.Texts() {
+			if tag, err := normalizeTag(category); err == nil {
+				input.Tags = append(input.Tags, tag)
+			}
+		}
+	}
+
+	item.TaskId, err = s.tasks.Create(ctx, input)
+	switch {
+	case errors.Is(err, repository.ErrAlreadyExists):
+		item.Status, item.Reason = task_manager.ImportSkipped, "already imported"
+	case err != nil:
+		return fail(err.Error())
+	default:
+		item.Status = task_manager.ImportCreated
+	}
+	return item
+}
+
+// importPriority maps the 1 (highest) to 9 priority of RFC 5545, where 0
+// means undefined.
+func importPriority(component *ical.Component) task_manager.Priority {
+	prop, _ := component.Get("PRIORITY")
+	priority, _ := strconv.Atoi(prop.Value)
+	switch {
+	case priority == 0 || priority == 5:
+		return task_manager.PriorityNormal
+	case priority <= 2:
+		return task_manager.PriorityUrgent
+	case priority <= 4:
+		return task_manager.PriorityHigh
+	default:
+		return task_manager.PriorityLow
+	}
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+type ChecklistService struct {
+	repo   repository.Checklist
+	tasks  TaskManagerTask
+	tx     repository.Transactor
+	logger *slog.Logger
+}
+
+func NewChecklistService(repo repository.Checklist, tasks TaskManagerTask, tx repository.Transactor, logger *slog.Logger) *ChecklistService {
+	return &ChecklistService{repo: repo, tasks: tasks, tx: tx, logger: logger}
+}
+
+func (s *ChecklistService) GetAll(ctx context.Context, taskId int) (items []task_manager.ChecklistItem, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	if _, err = s.tasks.GetById(ctx, taskId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAll(ctx, taskId)
+}
+
+func (s *ChecklistService) Create(ctx context.Context, taskId int, input task_manager.ChecklistItemInput) (item task_manager.ChecklistItem, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	if _, err = s.tasks.GetById(ctx, taskId); err != nil {
+		return item, err
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		item, err = repos.Checklist.Create(ctx, taskId, input)
+		return err
+	})
+	return item, err
+}
+
+func (s *ChecklistService) Update(ctx context.Context, taskId, itemId int, input task_manager.UpdateChecklistItemInput) (item task_manager.ChecklistItem, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("checklist_item.id", itemId))
+	if input.Empty() {
+		return item, ErrNothingToUpdate
+	}
+	err = s.tx.DoSerializable(ctx, func(repos *repository.Repository) error {
+		if item, err = repos.Checklist.Update(ctx, taskId, itemId, input); err != nil {
+			return err
+		}
+		if item.Done {
+			return s.autoComplete(ctx, repos, taskId)
+		}
+		return nil
+	})
+	return item, err
+}
+
+func (s *ChecklistService) Toggle(ctx context.Context, taskId, itemId int) (item task_manager.ChecklistItem, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.Toggle")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("checklist_item.id", itemId))
+	err = s.tx.DoSerializable(ctx, func(repos *repository.Repository) error {
+		if item, err = repos.Checklist.Toggle(ctx, taskId, itemId); err != nil {
+			return err
+		}
+		if item.Done {
+			return s.autoComplete(ctx, repos, taskId)
+		}
+		return nil
+	})
+	return item, err
+}
+
+func (s *ChecklistService) Reorder(ctx context.Context, taskId int, input task_manager.ChecklistOrderInput) (items []task_manager.ChecklistItem, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.Reorder")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("checklist_items.count", len(input.ItemIds)))
+	if _, err = s.tasks.GetById(ctx, taskId); err != nil {
+		return nil, err
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if err = repos.Checklist.Reorder(ctx, taskId, input.ItemIds); err != nil {
+			return err
+		}
+		items, err = repos.Checklist.GetAll(ctx, taskId)
+		return err
+	})
+	return items, err
+}
+
+// Delete removes the item; removing the last unfinished item may complete the task.
+func (s *ChecklistService) Delete(ctx context.Context, taskId, itemId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ChecklistService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("checklist_item.id", itemId))
+	return s.tx.DoSerializable(ctx, func(repos *repository.Repository) error {
+		if err = repos.Checklist.Delete(ctx, taskId, itemId); err != nil {
+			return err
+		}
+		return s.autoComplete(ctx, repos, taskId)
+	})
+}
+
+// autoComplete completes an unfinished task with checklist auto completion
+// once all of its checklist items are done. It runs in the serializable
+// transaction changing the item, so concurrent changes of the last items
+// cannot both miss the completion.
+func (s *ChecklistService) autoComplete(ctx context.Context, repos *repository.Repository, taskId int) error {
+	task, err := repos.TaskManagerTask.GetById(ctx, taskId)
+	if err != nil {
+		return err
+	}
+	if !task.ChecklistAutoComplete || task.StatusEnd != task_manager.Start {
+		return nil
+	}
+	total, done, err := repos.Checklist.Progress(ctx, taskId)
+	if err != nil || total == 0 || done < total {
+		return err
+	}
+	if task, err = repos.TaskManagerTask.Complete(ctx, taskId, 0); err != nil {
+		return err
+	}
+	if err = publish(ctx, repos, task_manager.EventTaskCompleted, task.TelegramId, task); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "task completed by checklist", "task_id", taskId)
+	return nil
+}
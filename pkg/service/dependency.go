@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+var (
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	ErrForeignTask     = errors.New("tasks belong to different users")
+)
+
+type DependencyService struct {
+	repo  repository.Dependency
+	tasks repository.TaskManagerTask
+	lists repository.List
+	tx    repository.Transactor
+}
+
+func NewDependencyService(repo repository.Dependency, tasks repository.TaskManagerTask, lists repository.List, tx repository.Transactor) *DependencyService {
+	return &DependencyService{repo: repo, tasks: tasks, lists: lists, tx: tx}
+}
+
+// GetBlockers returns the tasks the task waits for.
+func (s *DependencyService) GetBlockers(ctx context.Context, taskId int) (blockers []task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DependencyService.GetBlockers")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	task, err := s.tasks.GetById(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(task.BlockedBy))
+	for i, id := range task.BlockedBy {
+		ids[i] = int(id)
+	}
+	blockers, err = s.tasks.GetByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if blockers == nil {
+		blockers = make([]task_manager.Task, 0)
+	}
+	return blockers, nil
+}
+
+// Add makes the task wait for another task of the same user unless that
+// closes a cycle. Dependencies of the user are locked while they are
+// checked, so concurrent additions cannot close a cycle together.
+func (s *DependencyService) Add(ctx context.Context, taskId int, input task_manager.TaskDependencyInput) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DependencyService.Add")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.blocked_by_id", input.BlockedById))
+	if taskId == input.BlockedById {
+		return task, ErrDependencyCycle
+	}
+	task, err = s.tasks.GetById(ctx, taskId)
+	if err != nil {
+		return task, err
+	}
+	blocker, err := s.tasks.GetById(ctx, input.BlockedById)
+	if err != nil {
+		return task, err
+	}
+	if task.TelegramId != blocker.TelegramId {
+		return task, ErrForeignTask
+	}
+
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if err := repos.Lock(ctx, repository.LockDependencies, task.TelegramId); err != nil {
+			return err
+		}
+		deps, err := repos.Dependency.GetByOwner(ctx, task.TelegramId)
+		if err != nil {
+			return err
+		}
+		if dependsOn(deps, input.BlockedById, taskId) {
+			return ErrDependencyCycle
+		}
+		return repos.Dependency.Add(ctx, taskId, input.BlockedById)
+	})
+	if err != nil {
+		return task, err
+	}
+	return s.tasks.GetById(ctx, taskId)
+}
+
+func (s *DependencyService) Remove(ctx context.Context, taskId, blockedById int) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DependencyService.Remove")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.blocked_by_id", blockedById))
+	if err = s.repo.Remove(ctx, taskId, blockedById); err != nil {
+		return task, err
+	}
+	return s.tasks.GetById(ctx, taskId)
+}
+
+// GetListGraph returns the tasks of the list with their dependencies,
+// including blockers and dependants outside of the list.
+func (s *DependencyService) GetListGraph(ctx context.Context, listId int) (graph task_manager.DependencyGraph, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DependencyService.GetListGraph")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId))
+	list, err := s.lists.GetById(ctx, listId)
+	if err != nil {
+		return graph, err
+	}
+	graph.Nodes, err = s.tasks.GetAll(ctx, list.TelegramId, task_manager.TaskFilter{ListId: listId, Archived: true})
+	if err != nil {
+		return graph, err
+	}
+	graph.Edges, err = s.repo.GetByList(ctx, listId)
+	if err != nil {
+		return graph, err
+	}
+
+	known := make(map[int]bool, len(graph.Nodes))
+	for _, task := range graph.Nodes {
+		known[task.Id] = true
+	}
+	var outside []int
+	for _, edge := range graph.Edges {
+		for _, id := range []int{edge.TaskId, edge.BlockedById} {
+			if !known[id] {
+				known[id] = true
+				outside = append(outside, id)
+			}
+		}
+	}
+	if len(outside) == 0 {
+		return graph, nil
+	}
+	tasks, err := s.tasks.GetByIds(ctx, outside)
+	if err != nil {
+		return graph, err
+	}
+	graph.Nodes = append(graph.Nodes, tasks...)
+	return graph, nil
+}
+
+// dependsOn tells whether from waits for to, directly or through other
+// tasks, by a depth-first search over deps.
+func dependsOn(deps []task_manager.TaskDependency, from, to int) bool {
+	blockers := make(map[int][]int)
+	for _, dep := range deps {
+		blockers[dep.TaskId] = append(blockers[dep.TaskId], dep.BlockedById)
+	}
+
+	visited := make(map[int]bool)
+	stack := []int{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, blockers[id]...)
+	}
+	return false
+}
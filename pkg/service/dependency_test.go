@@ -0,0 +1,34 @@
+package service
+
+import (
+	"task_manager"
+	"testing"
+)
+
+func TestDependsOn(t *testing.T) {
+	dep := func(taskId, blockedById int) task_manager.TaskDependency {
+		return task_manager.TaskDependency{TaskId: taskId, BlockedById: blockedById}
+	}
+	tests := []struct {
+		name     string
+		deps     []task_manager.TaskDependency
+		from, to int
+		want     bool
+	}{
+		{name: "no dependencies", from: 1, to: 2},
+		{name: "same task", from: 1, to: 1, want: true},
+		{name: "direct", deps: []task_manager.TaskDependency{dep(1, 2)}, from: 1, to: 2, want: true},
+		{name: "reverse direction", deps: []task_manager.TaskDependency{dep(1, 2)}, from: 2, to: 1},
+		{name: "chain", deps: []task_manager.TaskDependency{dep(1, 2), dep(2, 3), dep(3, 4)}, from: 1, to: 4, want: true},
+		{name: "branch", deps: []task_manager.TaskDependency{dep(1, 2), dep(1, 3), dep(3, 5)}, from: 1, to: 5, want: true},
+		{name: "unrelated branch", deps: []task_manager.TaskDependency{dep(1, 2), dep(3, 4)}, from: 1, to: 4},
+		{name: "diamond", deps: []task_manager.TaskDependency{dep(1, 2), dep(1, 3), dep(2, 4), dep(3, 4)}, from: 1, to: 4, want: true},
+		{name: "existing cycle", deps: []task_manager.TaskDependency{dep(1, 2), dep(2, 3), dep(3, 1)}, from: 1, to: 4},
+		{name: "through a cycle", deps: []task_manager.TaskDependency{dep(1, 2), dep(2, 1), dep(2, 3)}, from: 1, to: 3, want: true},
+	}
+	for _, tt := range tests {
+		if got := dependsOn(tt.deps, tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: dependsOn(%d, %d) = %v, want %v", tt.name, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
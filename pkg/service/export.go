@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/export"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const maxImportRows = 10000
+
+var (
+	ErrUnknownFormat = export.ErrUnknownFormat
+	ErrTooManyRows   = fmt.Errorf("import is limited to %d rows", maxImportRows)
+	ErrEmptyImport   = errors.New("nothing to import")
+)
+
+// ImportError lists the invalid rows of a rejected import.
+type ImportError struct {
+	Rows []task_manager.ImportRowError
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("%d invalid rows, nothing imported", len(e.Rows))
+}
+
+// ExportService moves all tasks of a user in and out of the service as
+// CSV or JSON Lines.
+type ExportService struct {
+	repo   repository.TaskManagerTask
+	quota  Quota
+	tx     repository.Transactor
+	logger *slog.Logger
+}
+
+func NewExportService(repo repository.TaskManagerTask, quota Quota, tx repository.Transactor, logger *slog.Logger) *ExportService {
+	return &ExportService{repo: repo, quota: quota, tx: tx, logger: logger}
+}
+
+// Export writes the tasks of the user to w as they are read from the
+// database. A failure after the first write leaves w truncated.
+func (s *ExportService) Export(ctx context.Context, telegramId int, w io.Writer, format string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ExportService.Export")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId), attribute.String("export.format", format))
+	writer, err := export.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = s.repo.Export(ctx, telegramId, func(task task_manager.Task) error {
+		count++
+		return writer.Write(task_manager.NewTaskRecord(task))
+	})
+	if err != nil {
+		return err
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "tasks exported", "telegram_id", telegramId, "format", format, "count", count)
+	return nil
+}
+
+// Import validates every row of r and creates the tasks only if all rows
+// are valid. The tasks are created in one transaction after checking the
+// quota for all of them, and a task.created event is published for each.
+func (s *ExportService) Import(ctx context.Context, telegramId int, r io.Reader, format string) (ids []int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ExportService.Import")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId), attribute.String("export.format", format))
+	rows, rowErrors, err := export.Read(r, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows)+len(rowErrors) > maxImportRows {
+		return nil, ErrTooManyRows
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		return nil, ErrEmptyImport
+	}
+
+	now := time.Now().UTC()
+	records := make([]task_manager.TaskRecord, 0, len(rows))
+	texts := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if err := validateRecord(&row.Record, now); err != nil {
+			rowErrors = append(rowErrors, task_manager.ImportRowError{Row: row.Number, Message: err.Error()})
+			continue
+		}
+		records = append(records, row.Record)
+		texts = append(texts, row.Record.Text)
+	}
+	if len(rowErrors) > 0 {
+		slices.SortFunc(rowErrors, func(a, b task_manager.ImportRowError) int { return a.Row - b.Row })
+		return nil, &ImportError{Rows: rowErrors}
+	}
+
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if err := s.quota.CheckCreateAll(ctx, repos, telegramId, texts); err != nil {
+			return err
+		}
+		created, err := repos.TaskManagerTask.Import(ctx, telegramId, records)
+		if err != nil {
+			return err
+		}
+		for _, id := range created {
+			if err := publishTask(ctx, repos, task_manager.EventTaskCreated, id); err != nil {
+				return err
+			}
+		}
+		ids = created
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("import.count", len(ids)))
+	s.logger.InfoContext(ctx, "tasks imported", "telegram_id", telegramId, "format", format, "count", len(ids))
+	return ids, nil
+}
+
+// validateRecord fills defaults of the record and normalizes its tags the
+// way tasks created through the API are.
+func validateRecord(record *task_manager.TaskRecord, now time.Time) error {
+	record.Text = strings.TrimSpace(record.Text)
+	if record.Text == "" {
+		return errors.New("text is required")
+	}
+	if record.StartTime.IsZero() {
+		return errors.New("start_time is required")
+	}
+	record.StartTime = record.StartTime.UTC()
+	if record.DueAt != nil {
+		dueAt := record.DueAt.UTC()
+		record.DueAt = &dueAt
+	}
+
+	switch record.Status {
+	case "", task_manager.Start:
+		record.Status = task_manager.Start
+		record.CompletedAt = nil
+	case task_manager.End:
+		completedAt := now
+		if record.CompletedAt != nil {
+			completedAt = record.CompletedAt.UTC()
+		}
+		record.CompletedAt = &completedAt
+	default:
+		return fmt.Errorf("invalid status %q", record.Status)
+	}
+
+	if record.Priority == "" {
+		record.Priority = task_manager.PriorityNormal
+	}
+	if !record.Priority.Valid() {
+		return ErrInvalidPriority
+	}
+
+	tags := parseHashtags(record.Text)
+	for _, tag := range record.Tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return fmt.Errorf("%w %q", err, tag)
+		}
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	record.Tags = tags
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"time"
+)
+
+const (
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+	idempotencyPurgeInterval = 10 * time.Minute
+)
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight = errors.New("request with this idempotency key is still in progress")
+)
+
+type IdempotencyService struct {
+	repo   repository.IdempotencyKey
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func NewIdempotencyService(repo repository.IdempotencyKey, ttl time.Duration, logger *slog.Logger) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	return &IdempotencyService{repo: repo, ttl: ttl, logger: logger}
+}
+
+// Begin reserves key for a request with the given fingerprint. It returns
+// the stored record when the request was already completed and should be
+// replayed, or nil when the caller should process the request and then
+// call Complete or Release.
+func (s *IdempotencyService) Begin(ctx context.Context, principal, key, fingerprint string) (*task_manager.IdempotencyRecord, error) {
+	record, reserved, err := s.repo.Reserve(ctx, principal, key, fingerprint, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return &record, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, principal, key string, statusCode int, contentType string, response []byte) error {
+	return s.repo.Complete(ctx, principal, key, statusCode, contentType, response)
+}
+
+// Release forgets the key of a failed request so that it may be retried.
+func (s *IdempotencyService) Release(ctx context.Context, principal, key string) error {
+	return s.repo.Release(ctx, principal, key)
+}
+
+// RunPurger periodically deletes expired keys.
+func (s *IdempotencyService) RunPurger(ctx context.Context) error {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpired(ctx)
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				s.logger.DebugContext(ctx, "purged idempotency keys", "deleted", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+var (
+	ErrListArchived = errors.New("list is archived")
+	ErrForeignList  = errors.New("list belongs to another user")
+	ErrInvalidOrder = repository.ErrInvalidOrder
+)
+
+type ListService struct {
+	repo   repository.List
+	tx     repository.Transactor
+	logger *slog.Logger
+}
+
+func NewListService(repo repository.List, tx repository.Transactor, logger *slog.Logger) *ListService {
+	return &ListService{repo: repo, tx: tx, logger: logger}
+}
+
+func (s *ListService) GetAll(ctx context.Context, telegramId int, archived bool) (lists []task_manager.List, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	return s.repo.GetAll(ctx, telegramId, archived)
+}
+
+func (s *ListService) GetById(ctx context.Context, listId int) (list task_manager.List, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId))
+	return s.repo.GetById(ctx, listId)
+}
+
+func (s *ListService) Create(ctx context.Context, telegramId int, input task_manager.ListInput) (list task_manager.List, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		list, err = repos.List.Create(ctx, telegramId, input)
+		return err
+	})
+	if err != nil {
+		return list, err
+	}
+	s.logger.InfoContext(ctx, "list created", "list_id", list.Id, "telegram_id", telegramId)
+	return list, nil
+}
+
+func (s *ListService) Update(ctx context.Context, listId int, input task_manager.UpdateListInput) (list task_manager.List, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId))
+	if input.Empty() {
+		return list, ErrNothingToUpdate
+	}
+	return s.repo.Update(ctx, listId, input)
+}
+
+func (s *ListService) Delete(ctx context.Context, listId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId))
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		return repos.List.Delete(ctx, listId)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "list deleted", "list_id", listId)
+	return nil
+}
+
+// Archive archives the list with its tasks, or restores them when archived is false.
+func (s *ListService) Archive(ctx context.Context, listId int, archived bool) (list task_manager.List, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.Archive")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId), attribute.Bool("list.archived", archived))
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		list, err = repos.List.Archive(ctx, listId, archived)
+		return err
+	})
+	if err != nil {
+		return list, err
+	}
+	s.logger.InfoContext(ctx, "list archived", "list_id", listId, "archived", archived)
+	return list, nil
+}
+
+func (s *ListService) Reorder(ctx context.Context, listId int, input task_manager.ListOrderInput) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ListService.Reorder")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("list.id", listId), attribute.Int("tasks.count", len(input.TaskIds)))
+	return s.tx.Do(ctx, func(repos *repository.Repository) error {
+		return repos.List.Reorder(ctx, listId, input.TaskIds)
+	})
+}
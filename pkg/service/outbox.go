@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const (
+	defaultDispatcherInterval = 5 * time.Second
+	defaultMaxAttempts        = 10
+	dispatcherBatchSize       = 100
+	// dispatcherLease hides claimed events from other dispatchers while
+	// they are delivered.
+	dispatcherLease = time.Minute
+	minRetryDelay   = 5 * time.Second
+	maxRetryDelay   = time.Hour
+	// defaultOutboxRetention is how long delivered and dead events are kept,
+	// which also bounds how far event streams can be resumed.
+	defaultOutboxRetention = 7 * 24 * time.Hour
+	outboxPruneInterval    = time.Hour
+	outboxPruneBatchSize   = 1000
+)
+
+// Sink receives the events of the outbox. Deliver must be idempotent:
+// an event is delivered again when any sink fails or the process stops
+// before the delivery is recorded.
+type Sink interface {
+	Deliver(ctx context.Context, event task_manager.Event) error
+}
+
+// LogSink only logs events.
+type LogSink struct {
+	logger *slog.Logger
+}
+
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Deliver(ctx context.Context, event task_manager.Event) error {
+	s.logger.InfoContext(ctx, "event published",
+		"event_id", event.Id,
+		"event_type", event.Type,
+		"telegram_id", event.TelegramId,
+	)
+	return nil
+}
+
+type DispatcherConfig struct {
+	Interval time.Duration
+	// MaxAttempts moves events that failed this many times to the dead letters.
+	MaxAttempts int
+	// Retention is how long delivered and dead events are kept.
+	Retention time.Duration
+}
+
+// Dispatcher periodically delivers pending outbox events to the registered
+// sinks, retrying failed events with exponential backoff.
+type Dispatcher struct {
+	repo        repository.Outbox
+	sinks       []Sink
+	interval    time.Duration
+	maxAttempts int
+	retention   time.Duration
+	prunedAt    time.Time
+	logger      *slog.Logger
+}
+
+func NewDispatcher(repo repository.Outbox, config DispatcherConfig, logger *slog.Logger) *Dispatcher {
+	if config.Interval <= 0 {
+		config.Interval = defaultDispatcherInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultOutboxRetention
+	}
+	return &Dispatcher{repo: repo, interval: config.Interval, maxAttempts: config.MaxAttempts,
+		retention: config.Retention, logger: logger}
+}
+
+// AddSink registers a sink; call it before Run.
+func (d *Dispatcher) AddSink(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.tick(ctx)
+		}
+	}
+}
+
+// tick dispatches pending events until none are left and prunes old ones
+// every outboxPruneInterval.
+func (d *Dispatcher) tick(ctx context.Context) {
+	if time.Since(d.prunedAt) >= outboxPruneInterval {
+		if err := d.prune(ctx); err != nil {
+			d.logger.ErrorContext(ctx, "failed to prune events", "error", err)
+		} else {
+			d.prunedAt = time.Now()
+		}
+	}
+	for ctx.Err() == nil {
+		n, err := d.dispatchBatch(ctx)
+		if err != nil {
+			d.logger.ErrorContext(ctx, "failed to dispatch events", "error", err)
+			return
+		}
+		if n < dispatcherBatchSize {
+			return
+		}
+	}
+}
+
+// prune deletes delivered and dead events older than the retention.
+func (d *Dispatcher) prune(ctx context.Context) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Dispatcher.prune")
+	defer func() { tracing.End(span, err) }()
+
+	before := time.Now().UTC().Add(-d.retention)
+	var pruned int64
+	for ctx.Err() == nil {
+		n, err := d.repo.Prune(ctx, before, outboxPruneBatchSize)
+		if err != nil {
+			return err
+		}
+		pruned += n
+		if n < outboxPruneBatchSize {
+			break
+		}
+	}
+	if pruned > 0 {
+		d.logger.InfoContext(ctx, "events pruned", "count", pruned, "before", before)
+	}
+	return ctx.Err()
+}
+
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (n int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Dispatcher.dispatchBatch")
+	defer func() { tracing.End(span, err) }()
+
+	events, err := d.repo.ClaimPending(ctx, dispatcherBatchSize, dispatcherLease)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if err = d.dispatch(ctx, event); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// dispatch delivers the event to all sinks and records the outcome.
+func (d *Dispatcher) dispatch(ctx context.Context, event task_manager.Event) error {
+	var errs []error
+	for _, sink := range d.sinks {
+		if err := sink.Deliver(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return d.repo.MarkDelivered(ctx, event.Id)
+	}
+
+	reason := errors.Join(errs...).Error()
+	if event.Attempts >= d.maxAttempts {
+		d.logger.ErrorContext(ctx, "event moved to dead letters", "event_id", event.Id, "event_type", event.Type,
+			"attempts", event.Attempts, "error", reason)
+		return d.repo.MarkDead(ctx, event.Id, reason)
+	}
+	delay := backoff(event.Attempts, minRetryDelay, maxRetryDelay)
+	d.logger.WarnContext(ctx, "failed to deliver event", "event_id", event.Id, "event_type", event.Type,
+		"attempts", event.Attempts, "retry_in", delay, "error", reason)
+	return d.repo.MarkFailed(ctx, event.Id, delay, reason)
+}
+
+// backoff doubles the delay from minDelay after every failed attempt.
+func backoff(attempts int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// publishTask adds an event with the current state of the task to the
+// outbox of the transaction of repos.
+func publishTask(ctx context.Context, repos *repository.Repository, eventType task_manager.EventType, taskId int) error {
+	task, err := repos.TaskManagerTask.GetById(ctx, taskId)
+	if err != nil {
+		return err
+	}
+	return publish(ctx, repos, eventType, task.TelegramId, task)
+}
+
+// publish adds an event to the outbox of the transaction of repos.
+func publish(ctx context.Context, repos *repository.Repository, eventType task_manager.EventType, telegramId int, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = repos.Outbox.Add(ctx, task_manager.Event{Type: eventType, TelegramId: telegramId, Payload: data})
+	return err
+}
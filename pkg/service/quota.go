@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"unicode/utf8"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaError reports which limit of the user's tier blocked an operation.
+type QuotaError struct {
+	Limit   string
+	Max     int
+	Current int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s is limited to %d, current %d", e.Limit, e.Max, e.Current)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+type QuotaConfig struct {
+	// DefaultTier applies to users without an assigned tier or with a tier
+	// that is not configured.
+	DefaultTier string
+	Tiers       map[string]task_manager.QuotaLimits
+}
+
+type QuotaService struct {
+	repo   repository.Quota
+	config QuotaConfig
+}
+
+func NewQuotaService(repo repository.Quota, config QuotaConfig) *QuotaService {
+	return &QuotaService{repo: repo, config: config}
+}
+
+func (s *QuotaService) GetUsage(ctx context.Context, telegramId int) (usage task_manager.QuotaUsage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "QuotaService.GetUsage")
+	defer func() { tracing.End(span, err) }()
+
+	return s.usage(ctx, s.repo, telegramId)
+}
+
+func (s *QuotaService) usage(ctx context.Context, repo repository.Quota, telegramId int) (usage task_manager.QuotaUsage, err error) {
+	tier, err := repo.GetTier(ctx, telegramId)
+	if err != nil {
+		return usage, err
+	}
+	usage.TelegramId = telegramId
+	usage.Tier, usage.Limits = s.tier(tier)
+	usage.ActiveTasks, usage.TasksToday, err = repo.CountUsage(ctx, telegramId)
+	return usage, err
+}
+
+// tier returns the tier that applies to a user assigned tier, which is the
+// default tier when tier is empty or not configured, and its limits.
+func (s *QuotaService) tier(tier string) (string, task_manager.QuotaLimits) {
+	if limits, ok := s.config.Tiers[tier]; ok {
+		return tier, limits
+	}
+	return s.config.DefaultTier, s.config.Tiers[s.config.DefaultTier]
+}
+
+// CheckCreate returns a *QuotaError when the user may not create a task
+// with the given text. It must run in the transaction creating the task,
+// see CheckCreateAll.
+func (s *QuotaService) CheckCreate(ctx context.Context, repos *repository.Repository, telegramId int, text string) error {
+	return s.CheckCreateAll(ctx, repos, telegramId, []string{text})
+}
+
+// CheckCreateAll returns a *QuotaError when the user may not create tasks
+// with all of the given texts. It locks the usage of the user until the end
+// of the transaction of repos, so concurrent creations are counted one
+// after another; the tasks must be created in the same transaction.
+func (s *QuotaService) CheckCreateAll(ctx context.Context, repos *repository.Repository, telegramId int, texts []string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "QuotaService.CheckCreateAll")
+	defer func() { tracing.End(span, err) }()
+
+	if err = repos.Lock(ctx, repository.LockQuota, telegramId); err != nil {
+		return err
+	}
+	usage, err := s.usage(ctx, repos.Quota, telegramId)
+	if err != nil {
+		return err
+	}
+
+	limits := usage.Limits
+	for _, text := range texts {
+		if err = checkTextLength(limits, text); err != nil {
+			return err
+		}
+	}
+	if limits.MaxActiveTasks > 0 && usage.ActiveTasks+len(texts) > limits.MaxActiveTasks {
+		return &QuotaError{Limit: "max_active_tasks", Max: limits.MaxActiveTasks, Current: usage.ActiveTasks}
+	}
+	if limits.MaxTasksPerDay > 0 && usage.TasksToday+len(texts) > limits.MaxTasksPerDay {
+		return &QuotaError{Limit: "max_tasks_per_day", Max: limits.MaxTasksPerDay, Current: usage.TasksToday}
+	}
+	return nil
+}
+
+// CheckText returns a *QuotaError when the text of a task of the user is
+// longer than the tier allows.
+func (s *QuotaService) CheckText(ctx context.Context, telegramId int, text string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "QuotaService.CheckText")
+	defer func() { tracing.End(span, err) }()
+
+	tier, err := s.repo.GetTier(ctx, telegramId)
+	if err != nil {
+		return err
+	}
+	_, limits := s.tier(tier)
+	return checkTextLength(limits, text)
+}
+
+func checkTextLength(limits task_manager.QuotaLimits, text string) error {
+	if length := utf8.RuneCountInString(text); limits.MaxTextLength > 0 && length > limits.MaxTextLength {
+		return &QuotaError{Limit: "max_text_length", Max: limits.MaxTextLength, Current: length}
+	}
+	return nil
+}
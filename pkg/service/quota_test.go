@@ -0,0 +1,32 @@
+package service
+
+import (
+	"task_manager"
+	"testing"
+)
+
+func TestQuotaTier(t *testing.T) {
+	free := task_manager.QuotaLimits{MaxActiveTasks: 10, MaxTasksPerDay: 5, MaxTextLength: 100}
+	pro := task_manager.QuotaLimits{MaxActiveTasks: 100, MaxTasksPerDay: 50, MaxTextLength: 1000}
+	s := NewQuotaService(nil, QuotaConfig{
+		DefaultTier: "free",
+		Tiers:       map[string]task_manager.QuotaLimits{"free": free, "pro": pro},
+	})
+
+	tests := []struct {
+		assigned   string
+		wantTier   string
+		wantLimits task_manager.QuotaLimits
+	}{
+		{assigned: "", wantTier: "free", wantLimits: free},
+		{assigned: "pro", wantTier: "pro", wantLimits: pro},
+		{assigned: "free", wantTier: "free", wantLimits: free},
+		{assigned: "legacy", wantTier: "free", wantLimits: free},
+	}
+	for _, tt := range tests {
+		tier, limits := s.tier(tt.assigned)
+		if tier != tt.wantTier || limits != tt.wantLimits {
+			t.Errorf("tier(%q) = %q, %+v, want %q, %+v", tt.assigned, tier, limits, tt.wantTier, tt.wantLimits)
+		}
+	}
+}
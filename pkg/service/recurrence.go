@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxTemplateTasks = 100
+	// recurrenceHorizon bounds the search for occurrences of rules with UNTIL.
+	recurrenceHorizon = 5 * 366
+)
+
+var (
+	ErrInvalidRecurrence = errors.New("recurrence must be an RRULE with FREQ=DAILY, WEEKLY or MONTHLY and COUNT or UNTIL")
+	ErrInvalidTimeOfDay  = errors.New("times must be in the 15:04 format")
+	ErrTooManyTasks      = errors.New("template would create more than 100 tasks")
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// recurrence is the supported subset of RFC 5545 recurrence rules.
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    map[time.Weekday]bool
+}
+
+// parseRecurrence parses rules like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10".
+// An empty rule is a single occurrence.
+func parseRecurrence(rule string) (r recurrence, err error) {
+	r = recurrence{freq: "DAILY", interval: 1, count: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return r, nil
+	}
+
+	r.count = 0
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, ErrInvalidRecurrence
+		}
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+			if r.freq != "DAILY" && r.freq != "WEEKLY" && r.freq != "MONTHLY" {
+				return r, ErrInvalidRecurrence
+			}
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(value); err != nil || r.interval < 1 {
+				return r, ErrInvalidRecurrence
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(value); err != nil || r.count < 1 {
+				return r, ErrInvalidRecurrence
+			}
+		case "UNTIL":
+			if r.until, err = time.Parse("20060102", value[:min(len(value), 8)]); err != nil {
+				return r, ErrInvalidRecurrence
+			}
+		case "BYDAY":
+			r.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return r, ErrInvalidRecurrence
+				}
+				r.byDay[weekday] = true
+			}
+		default:
+			return r, ErrInvalidRecurrence
+		}
+	}
+	if r.count == 0 && r.until.IsZero() || r.byDay != nil && r.freq != "WEEKLY" {
+		return r, ErrInvalidRecurrence
+	}
+	return r, nil
+}
+
+// dates returns the days of the occurrences starting with the day of base,
+// keeping the clock time and location of base.
+func (r recurrence) dates(base time.Time) []time.Time {
+	var dates []time.Time
+	for day := 0; day < recurrenceHorizon; day++ {
+		date := base.AddDate(0, 0, day)
+		if !r.until.IsZero() && date.Format(time.DateOnly) > r.until.Format(time.DateOnly) {
+			break
+		}
+		if r.matches(base, date, day) {
+			dates = append(dates, date)
+			if len(dates) == r.count || len(dates) > maxTemplateTasks {
+				break
+			}
+		}
+	}
+	return dates
+}
+
+// matches tells whether date, day days after base, is an occurrence.
+// Weeks start on Monday.
+func (r recurrence) matches(base, date time.Time, day int) bool {
+	switch r.freq {
+	case "WEEKLY":
+		week := (day + (int(base.Weekday())+6)%7) / 7
+		if r.byDay == nil {
+			return date.Weekday() == base.Weekday() && week%r.interval == 0
+		}
+		return r.byDay[date.Weekday()] && week%r.interval == 0
+	case "MONTHLY":
+		months := (date.Year()-base.Year())*12 + int(date.Month()-base.Month())
+		return date.Day() == base.Day() && months%r.interval == 0
+	default:
+		return day%r.interval == 0
+	}
+}
+
+// seriesRule returns the RRULE that gives starts from the first of them,
+// or false when no rule of r does, as for a single start.
+func (r recurrence) seriesRule(starts []time.Time) (string, bool) {
+	if len(starts) < 2 {
+		return "", false
+	}
+	rule := "FREQ=" + r.freq + ";INTERVAL=" + strconv.Itoa(r.interval)
+	if r.byDay != nil {
+		var days []string
+		for _, name := range []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"} {
+			if r.byDay[weekdays[name]] {
+				days = append(days, name)
+			}
+		}
+		rule += ";BYDAY=" + strings.Join(days, ",")
+	}
+	rule += ";COUNT=" + strconv.Itoa(len(starts))
+
+	series, err := parseRecurrence(rule)
+	if err != nil {
+		return "", false
+	}
+	dates := series.dates(starts[0])
+	if len(dates) != len(starts) {
+		return "", false
+	}
+	for i, date := range dates {
+		if !date.Equal(starts[i]) {
+			return "", false
+		}
+	}
+	return rule, true
+}
+
+// parseTimeOfDay parses a "15:04" time of day into hours and minutes.
+func parseTimeOfDay(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, ErrInvalidTimeOfDay
+	}
+	return t.Hour(), t.Minute(), nil
+}
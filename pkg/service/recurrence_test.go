@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"task_manager"
+	"testing"
+	"time"
+)
+
+func TestSeriesRule(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 3, d, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		recurrence string
+		starts     []time.Time
+		want       string
+		wantOk     bool
+	}{
+		{name: "daily", recurrence: "FREQ=DAILY;COUNT=3",
+			starts: []time.Time{day(2, 8), day(3, 8), day(4, 8)},
+			want:   "FREQ=DAILY;INTERVAL=1;COUNT=3", wantOk: true},
+		{name: "until becomes count", recurrence: "FREQ=DAILY;INTERVAL=2;UNTIL=20260306",
+			starts: []time.Time{day(2, 8), day(4, 8), day(6, 8)},
+			want:   "FREQ=DAILY;INTERVAL=2;COUNT=3", wantOk: true},
+		{name: "weekdays", recurrence: "FREQ=WEEKLY;BYDAY=TH,MO;COUNT=3",
+			starts: []time.Time{day(2, 8), day(5, 8), day(9, 8)},
+			want:   "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,TH;COUNT=3", wantOk: true},
+		{name: "first day skipped", recurrence: "FREQ=DAILY;COUNT=3",
+			starts: []time.Time{day(3, 8), day(4, 8)},
+			want:   "FREQ=DAILY;INTERVAL=1;COUNT=2", wantOk: true},
+		{name: "single start", recurrence: "FREQ=DAILY;COUNT=1",
+			starts: []time.Time{day(2, 8)}},
+		{name: "starts off the rule", recurrence: "FREQ=DAILY;COUNT=3",
+			starts: []time.Time{day(2, 8), day(4, 8)}},
+		{name: "repeated start", recurrence: "FREQ=DAILY;COUNT=2",
+			starts: []time.Time{day(2, 8), day(2, 8), day(3, 8), day(3, 8)}},
+	}
+	for _, tt := range tests {
+		rec, err := parseRecurrence(tt.recurrence)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, ok := rec.seriesRule(tt.starts)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("%s: seriesRule = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+	}{
+		{rule: ""},
+		{rule: "RRULE:FREQ=DAILY;COUNT=3"},
+		{rule: "freq=weekly;byday=mo,th;until=20260401"},
+		{rule: "FREQ=MONTHLY;INTERVAL=2;UNTIL=20260401T000000Z"},
+		{rule: "FREQ=DAILY", wantErr: true},
+		{rule: "FREQ=YEARLY;COUNT=2", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0;COUNT=2", wantErr: true},
+		{rule: "FREQ=DAILY;BYDAY=MO;COUNT=2", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX;COUNT=2", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=2026;COUNT=2", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=8;COUNT=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := parseRecurrence(tt.rule); (err != nil) != tt.wantErr {
+			t.Errorf("parseRecurrence(%q) error = %v, want error %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRecurrenceDates(t *testing.T) {
+	// 2026-03-02 is a Monday.
+	base := time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 8, 30, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		rule string
+		base time.Time
+		want []time.Time
+	}{
+		{rule: "", base: base, want: []time.Time{base}},
+		{rule: "FREQ=DAILY;COUNT=3", base: base,
+			want: []time.Time{date(2026, 3, 2), date(2026, 3, 3), date(2026, 3, 4)}},
+		{rule: "FREQ=DAILY;INTERVAL=3;UNTIL=20260308", base: base,
+			want: []time.Time{date(2026, 3, 2), date(2026, 3, 5), date(2026, 3, 8)}},
+		{rule: "FREQ=WEEKLY;COUNT=3", base: base,
+			want: []time.Time{date(2026, 3, 2), date(2026, 3, 9), date(2026, 3, 16)}},
+		{rule: "FREQ=WEEKLY;BYDAY=WE,FR;COUNT=3", base: base,
+			want: []time.Time{date(2026, 3, 4), date(2026, 3, 6), date(2026, 3, 11)}},
+		// Weeks start on Monday, so a base on Saturday shares its week with
+		// the Sunday after it.
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;COUNT=3", base: date(2026, 3, 7),
+			want: []time.Time{date(2026, 3, 8), date(2026, 3, 16), date(2026, 3, 22)}},
+		{rule: "FREQ=MONTHLY;COUNT=3", base: date(2026, 1, 15),
+			want: []time.Time{date(2026, 1, 15), date(2026, 2, 15), date(2026, 3, 15)}},
+		// Months without the day of base are skipped.
+		{rule: "FREQ=MONTHLY;COUNT=3", base: date(2026, 1, 31),
+			want: []time.Time{date(2026, 1, 31), date(2026, 3, 31), date(2026, 5, 31)}},
+		{rule: "FREQ=MONTHLY;INTERVAL=6;UNTIL=20270101", base: date(2026, 1, 10),
+			want: []time.Time{date(2026, 1, 10), date(2026, 7, 10)}},
+	}
+	for _, tt := range tests {
+		rec, err := parseRecurrence(tt.rule)
+		if err != nil {
+			t.Fatalf("parseRecurrence(%q): %v", tt.rule, err)
+		}
+		got := rec.dates(tt.base)
+		if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+			t.Errorf("%q from %s: dates = %v, want %v", tt.rule, tt.base.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestRecurrenceDatesLimit(t *testing.T) {
+	rec, err := parseRecurrence("FREQ=DAILY;UNTIL=20300101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(rec.dates(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))); got != maxTemplateTasks+1 {
+		t.Errorf("dates returned %d dates, want %d to report the limit", got, maxTemplateTasks+1)
+	}
+}
+
+func TestTemplateStarts(t *testing.T) {
+	base := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		template task_manager.TaskTemplate
+		want     []time.Time
+		wantErr  error
+	}{
+		{name: "no times", template: task_manager.TaskTemplate{Recurrence: "FREQ=DAILY;COUNT=2"},
+			want: []time.Time{at(2, 12), at(3, 12)}},
+		{name: "times before base skipped", template: task_manager.TaskTemplate{
+			Recurrence: "FREQ=DAILY;COUNT=2", Times: []string{"08:00", "20:00"}},
+			want: []time.Time{at(2, 20), at(3, 8), at(3, 20)}},
+		{name: "invalid time", template: task_manager.TaskTemplate{Times: []string{"8am"}},
+			wantErr: ErrInvalidTimeOfDay},
+		{name: "too many tasks", template: task_manager.TaskTemplate{
+			Recurrence: "FREQ=DAILY;COUNT=60", Times: []string{"13:00", "14:00"}},
+			wantErr: ErrTooManyTasks},
+	}
+	for _, tt := range tests {
+		got, err := templateStarts(tt.template, base)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+			t.Errorf("%s: starts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 5, 0, 0, time.UTC)
+	got := renderTemplate("{n}. {weekday} {date} {time} {unknown}", start, 3)
+	if want := "3. Monday 2026-03-02 08:05 {unknown}"; got != want {
+		t.Errorf("renderTemplate = %q, want %q", got, want)
+	}
+}
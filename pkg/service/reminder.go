@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+var ErrInvalidReminder = errors.New("reminder needs exactly one of remind_at and offset_seconds")
+
+type ReminderService struct {
+	repo  repository.TaskReminder
+	tasks repository.TaskManagerTask
+	tx    repository.Transactor
+}
+
+func NewReminderService(repo repository.TaskReminder, tasks repository.TaskManagerTask, tx repository.Transactor) *ReminderService {
+	return &ReminderService{repo: repo, tasks: tasks, tx: tx}
+}
+
+func (s *ReminderService) Create(ctx context.Context, taskId int, input task_manager.TaskReminderInput) (reminder task_manager.TaskReminder, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReminderService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	if !input.Valid() {
+		return reminder, ErrInvalidReminder
+	}
+	task, err := s.tasks.GetById(ctx, taskId)
+	if err != nil {
+		return reminder, err
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if reminder, err = repos.TaskReminder.Create(ctx, taskId, input); err != nil {
+			return err
+		}
+		return publish(ctx, repos, task_manager.EventReminderCreated, task.TelegramId, reminder)
+	})
+	return reminder, err
+}
+
+func (s *ReminderService) GetAll(ctx context.Context, taskId int) (reminders []task_manager.TaskReminder, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReminderService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	if _, err = s.tasks.GetById(ctx, taskId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAll(ctx, taskId)
+}
+
+func (s *ReminderService) Update(ctx context.Context, taskId, reminderId int, input task_manager.TaskReminderInput) (reminder task_manager.TaskReminder, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReminderService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("reminder.id", reminderId))
+	if !input.Valid() {
+		return reminder, ErrInvalidReminder
+	}
+	return s.repo.Update(ctx, taskId, reminderId, input)
+}
+
+func (s *ReminderService) Delete(ctx context.Context, taskId, reminderId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReminderService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("reminder.id", reminderId))
+	return s.repo.Delete(ctx, taskId, reminderId)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const (
+	defaultSchedulerInterval = 30 * time.Second
+	schedulerBatchSize       = 100
+)
+
+// Notifier delivers a fired reminder to the user.
+type Notifier interface {
+	Notify(ctx context.Context, reminder task_manager.FiredReminder) error
+}
+
+// LogNotifier only logs fired reminders.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, reminder task_manager.FiredReminder) error {
+	n.logger.InfoContext(ctx, "reminder fired",
+		"reminder_id", reminder.Id,
+		"task_id", reminder.TaskId,
+		"telegram_id", reminder.TelegramId,
+		"fire_at", reminder.FireAt,
+	)
+	return nil
+}
+
+// Counter is satisfied by prometheus counters.
+type Counter interface {
+	Inc()
+}
+
+// ReminderSink hands reminder.fired events of the outbox to the notifier,
+// so failed notifications are retried by the dispatcher.
+type ReminderSink struct {
+	notifier Notifier
+	fired    Counter
+}
+
+func NewReminderSink(notifier Notifier, fired Counter) *ReminderSink {
+	return &ReminderSink{notifier: notifier, fired: fired}
+}
+
+func (s *ReminderSink) Deliver(ctx context.Context, event task_manager.Event) error {
+	if event.Type != task_manager.EventReminderFired {
+		return nil
+	}
+	var reminder task_manager.FiredReminder
+	if err := json.Unmarshal(event.Payload, &reminder); err != nil {
+		return err
+	}
+	if err := s.notifier.Notify(ctx, reminder); err != nil {
+		return err
+	}
+	s.fired.Inc()
+	return nil
+}
+
+// Scheduler periodically claims due reminders, each independently of the
+// other reminders of the same task, and publishes them as reminder.fired
+// events in the same transaction; ReminderSink delivers them.
+type Scheduler struct {
+	tx       repository.Transactor
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewScheduler(tx repository.Transactor, interval time.Duration, logger *slog.Logger) *Scheduler {
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+	return &Scheduler{tx: tx, interval: interval, logger: logger}
+}
+
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick fires due reminders until none are left.
+func (s *Scheduler) tick(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := s.fireBatch(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to fire reminders", "error", err)
+			return
+		}
+		if n < schedulerBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) fireBatch(ctx context.Context) (n int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Scheduler.fireBatch")
+	defer func() { tracing.End(span, err) }()
+
+	var reminders []task_manager.FiredReminder
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if reminders, err = repos.TaskReminder.ClaimDue(ctx, schedulerBatchSize); err != nil {
+			return err
+		}
+		for _, reminder := range reminders {
+			if err = publish(ctx, repos, task_manager.EventReminderFired, reminder.TelegramId, reminder); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(reminders), nil
+}
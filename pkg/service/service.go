@@ -1,25 +1,168 @@
 package service
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"task_manager"
+	"task_manager/pkg/ical"
 	"task_manager/pkg/repository"
+	"task_manager/pkg/webhook"
+	"time"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
 
 type TaskManagerTask interface {
-	Create(task task_manager.CreateTaskInput) (int, error)
-	GetAll(telegramId int) ([]task_manager.Task, error)
-	GetById(taskId int) (task_manager.Task, error)
-	Delete(taskId int) error
+	Create(ctx context.Context, task task_manager.CreateTaskInput) (int, error)
+	GetAll(ctx context.Context, telegramId int, filter task_manager.TaskFilter) ([]task_manager.Task, error)
+	GetById(ctx context.Context, taskId int) (task_manager.Task, error)
+	Update(ctx context.Context, taskId int, input task_manager.UpdateTaskInput, version int) (task_manager.Task, error)
+	Complete(ctx context.Context, taskId int, version int) (task_manager.Task, error)
+	Move(ctx context.Context, taskId int, input task_manager.MoveTaskInput, version int) (task_manager.Task, error)
+	Delete(ctx context.Context, taskId int, version int) error
+	Batch(ctx context.Context, input task_manager.BatchInput) (task_manager.BatchResult, error)
+}
+
+type Quota interface {
+	GetUsage(ctx context.Context, telegramId int) (task_manager.QuotaUsage, error)
+	CheckCreate(ctx context.Context, repos *repository.Repository, telegramId int, text string) error
+	CheckCreateAll(ctx context.Context, repos *repository.Repository, telegramId int, texts []string) error
+	CheckText(ctx context.Context, telegramId int, text string) error
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, principal, key, fingerprint string) (*task_manager.IdempotencyRecord, error)
+	Complete(ctx context.Context, principal, key string, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, principal, key string) error
+	RunPurger(ctx context.Context) error
+}
+
+type TaskReminder interface {
+	Create(ctx context.Context, taskId int, input task_manager.TaskReminderInput) (task_manager.TaskReminder, error)
+	GetAll(ctx context.Context, taskId int) ([]task_manager.TaskReminder, error)
+	Update(ctx context.Context, taskId, reminderId int, input task_manager.TaskReminderInput) (task_manager.TaskReminder, error)
+	Delete(ctx context.Context, taskId, reminderId int) error
+}
+
+type Tag interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.Tag, error)
+	Create(ctx context.Context, telegramId int, input task_manager.TagInput) (task_manager.Tag, error)
+	Rename(ctx context.Context, tagId int, input task_manager.TagInput) (task_manager.Tag, error)
+	Merge(ctx context.Context, tagId int, input task_manager.MergeTagsInput) (task_manager.Tag, error)
+	Delete(ctx context.Context, tagId int) error
+}
+
+type List interface {
+	GetAll(ctx context.Context, telegramId int, archived bool) ([]task_manager.List, error)
+	GetById(ctx context.Context, listId int) (task_manager.List, error)
+	Create(ctx context.Context, telegramId int, input task_manager.ListInput) (task_manager.List, error)
+	Update(ctx context.Context, listId int, input task_manager.UpdateListInput) (task_manager.List, error)
+	Delete(ctx context.Context, listId int) error
+	Archive(ctx context.Context, listId int, archived bool) (task_manager.List, error)
+	Reorder(ctx context.Context, listId int, input task_manager.ListOrderInput) error
+}
+
+type Checklist interface {
+	GetAll(ctx context.Context, taskId int) ([]task_manager.ChecklistItem, error)
+	Create(ctx context.Context, taskId int, input task_manager.ChecklistItemInput) (task_manager.ChecklistItem, error)
+	Update(ctx context.Context, taskId, itemId int, input task_manager.UpdateChecklistItemInput) (task_manager.ChecklistItem, error)
+	Toggle(ctx context.Context, taskId, itemId int) (task_manager.ChecklistItem, error)
+	Reorder(ctx context.Context, taskId int, input task_manager.ChecklistOrderInput) ([]task_manager.ChecklistItem, error)
+	Delete(ctx context.Context, taskId, itemId int) error
+}
+
+type Dependency interface {
+	GetBlockers(ctx context.Context, taskId int) ([]task_manager.Task, error)
+	Add(ctx context.Context, taskId int, input task_manager.TaskDependencyInput) (task_manager.Task, error)
+	Remove(ctx context.Context, taskId, blockedById int) (task_manager.Task, error)
+	GetListGraph(ctx context.Context, listId int) (task_manager.DependencyGraph, error)
+}
+
+type Template interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.TaskTemplate, error)
+	GetById(ctx context.Context, templateId int) (task_manager.TaskTemplate, error)
+	Create(ctx context.Context, telegramId int, input task_manager.TaskTemplateInput) (task_manager.TaskTemplate, error)
+	Update(ctx context.Context, templateId int, input task_manager.TaskTemplateInput) (task_manager.TaskTemplate, error)
+	Delete(ctx context.Context, templateId int) error
+	Instantiate(ctx context.Context, templateId int, input task_manager.InstantiateTemplateInput) ([]task_manager.Task, error)
+}
+
+type Calendar interface {
+	RotateToken(ctx context.Context, telegramId int) (string, error)
+	RevokeToken(ctx context.Context, telegramId int) error
+	Feed(ctx context.Context, telegramId int, token string) (task_manager.CalendarFeed, error)
+	Import(ctx context.Context, telegramId int, calendar *ical.Component, loc *time.Location) (task_manager.CalendarImportReport, error)
+}
+
+type Export interface {
+	Export(ctx context.Context, telegramId int, w io.Writer, format string) error
+	Import(ctx context.Context, telegramId int, r io.Reader, format string) ([]int, error)
+}
+
+type Webhook interface {
+	GetAll(ctx context.Context, telegramId int) ([]task_manager.Webhook, error)
+	GetById(ctx context.Context, webhookId int) (task_manager.Webhook, error)
+	Create(ctx context.Context, telegramId int, input task_manager.WebhookInput) (task_manager.Webhook, error)
+	Update(ctx context.Context, webhookId int, input task_manager.WebhookInput) (task_manager.Webhook, error)
+	Delete(ctx context.Context, webhookId int) error
+	GetDeliveries(ctx context.Context, webhookId int, limit int) ([]task_manager.WebhookDelivery, error)
+	SendTest(ctx context.Context, webhookId int) (task_manager.WebhookDelivery, error)
+	Deliver(ctx context.Context, event task_manager.Event) error
+	RunDelivery(ctx context.Context) error
+}
+
+type Stream interface {
+	Subscribe(ctx context.Context, telegramId int) (*Subscription, error)
+	Close()
+	Run(ctx context.Context) error
+}
+
+type Config struct {
+	Quotas            QuotaConfig
+	IdempotencyKeyTTL time.Duration
+	// WebhookMaxAttempts is the number of attempts of a webhook delivery.
+	WebhookMaxAttempts int
+	// WebhookAllowPrivate lets webhooks connect to loopback, private and
+	// link-local addresses, for local testing.
+	WebhookAllowPrivate bool
+	// StreamInterval is how often the event stream reads new events.
+	StreamInterval time.Duration
 }
 
 type Service struct {
 	TaskManagerTask
+	Quota
+	Idempotency
+	TaskReminder
+	Tag
+	List
+	Checklist
+	Dependency
+	Template
+	Calendar
+	Export
+	Webhook
+	Stream
 }
 
-func NewService(repos *repository.Repository) *Service {
+func NewService(repos *repository.Repository, logger *slog.Logger, config Config) *Service {
+	quota := NewQuotaService(repos.Quota, config.Quotas)
+	tasks := NewTaskService(repos.TaskManagerTask, repos.List, quota, repos, logger)
+	reminders := NewReminderService(repos.TaskReminder, repos.TaskManagerTask, repos)
 	return &Service{
-		TaskManagerTask: NewTaskService(repos.TaskManagerTask),
+		TaskManagerTask: tasks,
+		Quota:           quota,
+		Idempotency:     NewIdempotencyService(repos.IdempotencyKey, config.IdempotencyKeyTTL, logger),
+		TaskReminder:    reminders,
+		Tag:             NewTagService(repos.Tag, repos),
+		List:            NewListService(repos.List, repos, logger),
+		Checklist:       NewChecklistService(repos.Checklist, tasks, repos, logger),
+		Dependency:      NewDependencyService(repos.Dependency, repos.TaskManagerTask, repos.List, repos),
+		Template:        NewTemplateService(repos.Template, quota, repos, logger),
+		Calendar:        NewCalendarService(repos.Calendar, tasks, logger),
+		Export:          NewExportService(repos.TaskManagerTask, quota, repos, logger),
+		Webhook:         NewWebhookService(repos.Webhook, webhook.NewClient(webhook.NewHTTPClient(config.WebhookAllowPrivate)), config.WebhookMaxAttempts, logger),
+		Stream:          NewEventStream(repos.Outbox, config.StreamInterval, logger),
 	}
 }
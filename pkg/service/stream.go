@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"sync"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+const (
+	defaultStreamInterval = time.Second
+	streamBatchSize       = 500
+	// streamBufferSize is the number of events a subscriber may lag behind
+	// before it is dropped.
+	streamBufferSize = 64
+)
+
+var (
+	ErrStreamClosed = errors.New("event stream is closed")
+	// ErrReplayUnavailable means the events after the last received one
+	// may have been pruned from the outbox and cannot be replayed.
+	ErrReplayUnavailable = errors.New("events to replay are no longer available")
+)
+
+// EventStream follows the outbox and fans new events out to subscribers
+// of their users. Events are read in commit order, once no running
+// transaction can publish events before them, so rolled back and slow
+// transactions neither hold back nor lose events of others.
+type EventStream struct {
+	repo     repository.Outbox
+	interval time.Duration
+	logger   *slog.Logger
+
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	mu          sync.Mutex
+	cursor      task_manager.StreamPosition
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewEventStream(repo repository.Outbox, interval time.Duration, logger *slog.Logger) *EventStream {
+	if interval <= 0 {
+		interval = defaultStreamInterval
+	}
+	return &EventStream{
+		repo:        repo,
+		interval:    interval,
+		logger:      logger,
+		ready:       make(chan struct{}),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events of a user published after it was made.
+type Subscription struct {
+	stream     *EventStream
+	telegramId int
+	// cursor is the position of the last event published before the
+	// subscription.
+	cursor task_manager.StreamPosition
+	events chan task_manager.Event
+}
+
+// Events is closed when the subscription falls behind or the stream closes.
+func (s *Subscription) Events() <-chan task_manager.Event {
+	return s.events
+}
+
+// Replay passes events of the user after the event lastEventId that were
+// published before the subscription to fn. It returns ErrReplayUnavailable
+// when that event is no longer in the outbox.
+func (s *Subscription) Replay(ctx context.Context, lastEventId int64, fn func(task_manager.Event) error) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "EventStream.Replay")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", s.telegramId), attribute.Int64("event.id", lastEventId))
+	last, err := s.stream.repo.GetById(ctx, lastEventId)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && last.TelegramId != s.telegramId {
+		return ErrReplayUnavailable
+	}
+	if err != nil {
+		return err
+	}
+
+	position := last.Position()
+	for position.Before(s.cursor) {
+		events, err := s.stream.repo.GetByTelegramId(ctx, s.telegramId, position, s.cursor, streamBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err = fn(event); err != nil {
+				return err
+			}
+		}
+		if len(events) < streamBatchSize {
+			return nil
+		}
+		position = events[len(events)-1].Position()
+	}
+	return nil
+}
+
+func (s *Subscription) Close() {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+
+	s.stream.unsubscribe(s)
+}
+
+// Subscribe waits until the stream has started and subscribes to events of
+// the user.
+func (s *EventStream) Subscribe(ctx context.Context, telegramId int) (*Subscription, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ready:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrStreamClosed
+	}
+	sub := &Subscription{
+		stream:     s,
+		telegramId: telegramId,
+		cursor:     s.cursor,
+		events:     make(chan task_manager.Event, streamBufferSize),
+	}
+	s.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close ends all subscriptions and rejects new ones; call it on shutdown
+// so that open streams do not hold the server.
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		s.unsubscribe(sub)
+	}
+}
+
+// Run follows the outbox starting from its latest event.
+func (s *EventStream) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.tick(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "failed to read events", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick publishes new events until none are left.
+func (s *EventStream) tick(ctx context.Context) error {
+	select {
+	case <-s.ready:
+	default:
+		cursor, err := s.repo.LastPosition(ctx)
+		if err != nil {
+			return err
+		}
+		s.cursor = cursor
+		s.readyOnce.Do(func() { close(s.ready) })
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		events, err := s.repo.GetAfter(ctx, s.cursor, streamBatchSize)
+		if err != nil {
+			return err
+		}
+		s.publish(events)
+		if len(events) < streamBatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// publish passes events on to subscribers.
+func (s *EventStream) publish(events []task_manager.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		s.cursor = event.Position()
+		for sub := range s.subscribers {
+			if sub.telegramId != event.TelegramId {
+				continue
+			}
+			select {
+			case sub.events <- event:
+			default:
+				s.logger.Warn("event stream subscriber is too slow", "telegram_id", sub.telegramId)
+				s.unsubscribe(sub)
+			}
+		}
+	}
+}
+
+// unsubscribe must be called with s.mu held.
+func (s *EventStream) unsubscribe(sub *Subscription) {
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"regexp"
+	"slices"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+var (
+	ErrInvalidTag     = errors.New("tag name may contain only letters, digits and underscores")
+	ErrInvalidTagMode = errors.New("invalid tag mode")
+	ErrMergeTag       = errors.New("tags can be merged only into another tag of the same user")
+	ErrTagExists      = repository.ErrAlreadyExists
+)
+
+var (
+	hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+	tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+// parseHashtags returns the distinct lower case hashtags of text in order of appearance.
+func parseHashtags(text string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// normalizeTag lower cases name and strips the leading '#'.
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if !tagNamePattern.MatchString(name) {
+		return "", ErrInvalidTag
+	}
+	return name, nil
+}
+
+// normalizeTags normalizes names and drops duplicates, keeping the order.
+func normalizeTags(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+type TagService struct {
+	repo repository.Tag
+	tx   repository.Transactor
+}
+
+func NewTagService(repo repository.Tag, tx repository.Transactor) *TagService {
+	return &TagService{repo: repo, tx: tx}
+}
+
+func (s *TagService) GetAll(ctx context.Context, telegramId int) (tags []task_manager.Tag, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	return s.repo.GetAll(ctx, telegramId)
+}
+
+func (s *TagService) Create(ctx context.Context, telegramId int, input task_manager.TagInput) (tag task_manager.Tag, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	name, err := normalizeTag(input.Name)
+	if err != nil {
+		return tag, err
+	}
+	return s.repo.Create(ctx, telegramId, name)
+}
+
+// Rename also rewrites the hashtag in the texts of the tagged tasks.
+func (s *TagService) Rename(ctx context.Context, tagId int, input task_manager.TagInput) (tag task_manager.Tag, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagService.Rename")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("tag.id", tagId))
+	name, err := normalizeTag(input.Name)
+	if err != nil {
+		return tag, err
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		tag, err = repos.Tag.Rename(ctx, tagId, name)
+		return err
+	})
+	return tag, err
+}
+
+func (s *TagService) Merge(ctx context.Context, tagId int, input task_manager.MergeTagsInput) (tag task_manager.Tag, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagService.Merge")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("tag.id", tagId), attribute.Int("tag.into_id", input.IntoId))
+	if tagId == input.IntoId {
+		return tag, ErrMergeTag
+	}
+	from, err := s.repo.GetById(ctx, tagId)
+	if err != nil {
+		return tag, err
+	}
+	into, err := s.repo.GetById(ctx, input.IntoId)
+	if err != nil {
+		return tag, err
+	}
+	if from.TelegramId != into.TelegramId {
+		return tag, ErrMergeTag
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		tag, err = repos.Tag.Merge(ctx, tagId, input.IntoId)
+		return err
+	})
+	return tag, err
+}
+
+func (s *TagService) Delete(ctx context.Context, tagId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("tag.id", tagId))
+	return s.repo.Delete(ctx, tagId)
+}
@@ -1,30 +1,238 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
 	"task_manager"
 	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+)
+
+var (
+	ErrInvalidTelegramId = errors.New("invalid telegram id")
+	ErrNothingToUpdate   = errors.New("nothing to update")
+	ErrInvalidPriority   = errors.New("invalid priority")
+	ErrInvalidSort       = errors.New("invalid sort")
+	ErrClearDueAt        = errors.New("due_at cannot be set and cleared at once")
+	ErrNotFound          = repository.ErrNotFound
+	ErrVersionMismatch   = repository.ErrVersionMismatch
 )
 
 type TaskService struct {
-	repo repository.TaskManagerTask
+	repo   repository.TaskManagerTask
+	lists  repository.List
+	quota  Quota
+	tx     repository.Transactor
+	logger *slog.Logger
 }
 
-func NewTaskService(repo repository.TaskManagerTask) *TaskService {
-	return &TaskService{repo: repo}
+func NewTaskService(repo repository.TaskManagerTask, lists repository.List, quota Quota, tx repository.Transactor, logger *slog.Logger) *TaskService {
+	return &TaskService{repo: repo, lists: lists, quota: quota, tx: tx, logger: logger}
 }
 
-func (s *TaskService) Create(task task_manager.CreateTaskInput) (int, error) {
-	return s.repo.Create(task, task_manager.Start)
+func (s *TaskService) Create(ctx context.Context, task task_manager.CreateTaskInput) (id int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	telegramId, err := strconv.Atoi(task.TelegramId)
+	if err != nil {
+		return 0, ErrInvalidTelegramId
+	}
+	if task.Priority == "" {
+		task.Priority = task_manager.PriorityNormal
+	}
+	if !task.Priority.Valid() {
+		return 0, ErrInvalidPriority
+	}
+	tags := parseHashtags(task.Text)
+	for _, tag := range task.Tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return 0, err
+		}
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	if task.ListId != nil {
+		if err = s.checkList(ctx, telegramId, *task.ListId); err != nil {
+			return 0, err
+		}
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if err = s.quota.CheckCreate(ctx, repos, telegramId, task.Text); err != nil {
+			return err
+		}
+		if id, err = repos.TaskManagerTask.Create(ctx, task, task_manager.Start); err != nil {
+			return err
+		}
+		if err = repos.Tag.SetTaskTags(ctx, telegramId, id, tags); err != nil {
+			return err
+		}
+		return publishTask(ctx, repos, task_manager.EventTaskCreated, id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	span.SetAttributes(attribute.Int("task.id", id))
+	s.logger.InfoContext(ctx, "task created", "task_id", id, "telegram_id", task.TelegramId)
+	return id, nil
 }
 
-func (s *TaskService) GetAll(telegramId int) ([]task_manager.Task, error) {
-	return s.repo.GetAll(telegramId)
+func (s *TaskService) GetAll(ctx context.Context, telegramId int, filter task_manager.TaskFilter) (tasks []task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId), attribute.String("tasks.sort", string(filter.Sort)),
+		attribute.Int("list.id", filter.ListId))
+	if !filter.Sort.Valid() {
+		return nil, ErrInvalidSort
+	}
+	switch filter.TagMode {
+	case "":
+		filter.TagMode = task_manager.TagModeAnd
+	case task_manager.TagModeAnd, task_manager.TagModeOr:
+	default:
+		return nil, ErrInvalidTagMode
+	}
+	if filter.Tags, err = normalizeTags(filter.Tags); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAll(ctx, telegramId, filter)
 }
 
-func (s *TaskService) GetById(taskId int) (task_manager.Task, error) {
-	return s.repo.GetById(taskId)
+func (s *TaskService) GetById(ctx context.Context, taskId int) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId))
+	return s.repo.GetById(ctx, taskId)
 }
 
-func (s *TaskService) Delete(taskId int) error {
-	return s.repo.Delete(taskId)
+// Update changes the task if it is still at version; zero skips the check.
+func (s *TaskService) Update(ctx context.Context, taskId int, input task_manager.UpdateTaskInput, version int) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.version", version))
+	if input.Empty() {
+		return task, ErrNothingToUpdate
+	}
+	if input.Priority != nil && !input.Priority.Valid() {
+		return task, ErrInvalidPriority
+	}
+	if input.ClearDueAt && input.DueAt != nil {
+		return task, ErrClearDueAt
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if task, err = repos.TaskManagerTask.Update(ctx, taskId, input, version); err != nil {
+			return err
+		}
+		if input.Text != nil {
+			if err = s.quota.CheckText(ctx, task.TelegramId, task.Text); err != nil {
+				return err
+			}
+			tags := parseHashtags(task.Text)
+			if err = repos.Tag.SetTaskTags(ctx, task.TelegramId, taskId, tags); err != nil {
+				return err
+			}
+			sort.Strings(tags)
+			task.Tags = tags
+		}
+		return publish(ctx, repos, task_manager.EventTaskUpdated, task.TelegramId, task)
+	})
+	if err != nil {
+		return task, err
+	}
+	s.logger.InfoContext(ctx, "task updated", "task_id", taskId, "version", task.Version)
+	return task, nil
+}
+
+func (s *TaskService) Complete(ctx context.Context, taskId int, version int) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Complete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.version", version))
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if task, err = repos.TaskManagerTask.Complete(ctx, taskId, version); err != nil {
+			return err
+		}
+		return publish(ctx, repos, task_manager.EventTaskCompleted, task.TelegramId, task)
+	})
+	if err != nil {
+		return task, err
+	}
+	s.logger.InfoContext(ctx, "task completed", "task_id", taskId)
+	return task, nil
+}
+
+// Move puts the task into another list of its owner or, when input.ListId
+// is nil, out of lists.
+func (s *TaskService) Move(ctx context.Context, taskId int, input task_manager.MoveTaskInput, version int) (task task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Move")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.version", version))
+	if input.ListId != nil {
+		task, err = s.repo.GetById(ctx, taskId)
+		if err != nil {
+			return task, err
+		}
+		if err = s.checkList(ctx, task.TelegramId, *input.ListId); err != nil {
+			return task, err
+		}
+	}
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if task, err = repos.TaskManagerTask.Move(ctx, taskId, input.ListId, input.Position, version); err != nil {
+			return err
+		}
+		return publish(ctx, repos, task_manager.EventTaskUpdated, task.TelegramId, task)
+	})
+	if err != nil {
+		return task, err
+	}
+	s.logger.InfoContext(ctx, "task moved", "task_id", taskId, "position", task.Position)
+	return task, nil
+}
+
+// checkList makes sure tasks of the user can be put into the list.
+func (s *TaskService) checkList(ctx context.Context, telegramId, listId int) error {
+	list, err := s.lists.GetById(ctx, listId)
+	if err != nil {
+		return err
+	}
+	if list.TelegramId != telegramId {
+		return ErrForeignList
+	}
+	if list.ArchivedAt != nil {
+		return ErrListArchived
+	}
+	return nil
+}
+
+func (s *TaskService) Delete(ctx context.Context, taskId int, version int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TaskService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("task.id", taskId), attribute.Int("task.version", version))
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		task, err := repos.TaskManagerTask.GetById(ctx, taskId)
+		if err != nil {
+			return err
+		}
+		if err = repos.TaskManagerTask.Delete(ctx, taskId, version); err != nil {
+			return err
+		}
+		return publish(ctx, repos, task_manager.EventTaskDeleted, task.TelegramId, task_manager.DeletedTask{Id: taskId})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "task deleted", "task_id", taskId)
+	return nil
 }
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"time"
+)
+
+type TemplateService struct {
+	repo   repository.Template
+	quota  Quota
+	tx     repository.Transactor
+	logger *slog.Logger
+}
+
+func NewTemplateService(repo repository.Template, quota Quota, tx repository.Transactor, logger *slog.Logger) *TemplateService {
+	return &TemplateService{repo: repo, quota: quota, tx: tx, logger: logger}
+}
+
+func (s *TemplateService) GetAll(ctx context.Context, telegramId int) (templates []task_manager.TaskTemplate, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	return s.repo.GetAll(ctx, telegramId)
+}
+
+func (s *TemplateService) GetById(ctx context.Context, templateId int) (template task_manager.TaskTemplate, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("template.id", templateId))
+	return s.repo.GetById(ctx, templateId)
+}
+
+func (s *TemplateService) Create(ctx context.Context, telegramId int, input task_manager.TaskTemplateInput) (template task_manager.TaskTemplate, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	if input, err = validateTemplate(input); err != nil {
+		return template, err
+	}
+	return s.repo.Create(ctx, telegramId, input)
+}
+
+func (s *TemplateService) Update(ctx context.Context, templateId int, input task_manager.TaskTemplateInput) (template task_manager.TaskTemplate, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("template.id", templateId))
+	if input, err = validateTemplate(input); err != nil {
+		return template, err
+	}
+	return s.repo.Update(ctx, templateId, input)
+}
+
+func (s *TemplateService) Delete(ctx context.Context, templateId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("template.id", templateId))
+	return s.repo.Delete(ctx, templateId)
+}
+
+// Instantiate creates a task with the template reminders for every
+// occurrence of the template recurrence starting at input.BaseTime, all in
+// one transaction after checking the quota for all of them. Times of day
+// of the template before BaseTime are skipped. The tasks of every time of
+// day form a series, which calendar feeds export with an RRULE.
+func (s *TemplateService) Instantiate(ctx context.Context, templateId int, input task_manager.InstantiateTemplateInput) (tasks []task_manager.Task, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TemplateService.Instantiate")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("template.id", templateId))
+	template, err := s.repo.GetById(ctx, templateId)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := templateStarts(template, input.BaseTime)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(starts))
+	for i, start := range starts {
+		texts[i] = renderTemplate(template.Text, start, i+1)
+	}
+
+	err = s.tx.Do(ctx, func(repos *repository.Repository) error {
+		if err := s.quota.CheckCreateAll(ctx, repos, template.TelegramId, texts); err != nil {
+			return err
+		}
+		seriesIds := make([]*int, len(starts))
+		for _, series := range templateSeries(template, starts) {
+			id, err := repos.Calendar.CreateSeries(ctx, task_manager.TaskSeries{
+				TelegramId:  template.TelegramId,
+				Recurrence:  series.rule,
+				StartTimeAt: starts[series.starts[0]],
+			})
+			if err != nil {
+				return err
+			}
+			for _, i := range series.starts {
+				seriesIds[i] = &id
+			}
+		}
+		tasks = make([]task_manager.Task, 0, len(starts))
+		for i, start := range starts {
+			task, err := s.instantiate(ctx, repos, template, texts[i], start, seriesIds[i])
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "template instantiated", "template_id", templateId, "tasks", len(tasks))
+	return tasks, nil
+}
+
+// instantiate creates one task of the template with its tags and reminders
+// the way TaskService.Create and ReminderService.Create do.
+func (s *TemplateService) instantiate(ctx context.Context, repos *repository.Repository, template task_manager.TaskTemplate, text string, start time.Time, seriesId *int) (task task_manager.Task, err error) {
+	id, err := repos.TaskManagerTask.Create(ctx, task_manager.CreateTaskInput{
+		Text:       text,
+		StartTime:  start,
+		TelegramId: strconv.Itoa(template.TelegramId),
+		Priority:   template.Priority,
+		SeriesId:   seriesId,
+	}, task_manager.Start)
+	if err != nil {
+		return task, err
+	}
+	tags := parseHashtags(text)
+	for _, tag := range template.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if err = repos.Tag.SetTaskTags(ctx, template.TelegramId, id, tags); err != nil {
+		return task, err
+	}
+	if err = publishTask(ctx, repos, task_manager.EventTaskCreated, id); err != nil {
+		return task, err
+	}
+	for _, offset := range template.ReminderOffsets {
+		offset := int(offset)
+		reminder, err := repos.TaskReminder.Create(ctx, id, task_manager.TaskReminderInput{OffsetSeconds: &offset})
+		if err != nil {
+			return task, err
+		}
+		if err = publish(ctx, repos, task_manager.EventReminderCreated, template.TelegramId, reminder); err != nil {
+			return task, err
+		}
+	}
+	return repos.TaskManagerTask.GetById(ctx, id)
+}
+
+// templateStarts returns the start times of the tasks of the template.
+func templateStarts(template task_manager.TaskTemplate, base time.Time) ([]time.Time, error) {
+	rec, err := parseRecurrence(template.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	for _, date := range rec.dates(base) {
+		if len(template.Times) == 0 {
+			starts = append(starts, date)
+		}
+		for _, value := range template.Times {
+			hour, minute, err := parseTimeOfDay(value)
+			if err != nil {
+				return nil, err
+			}
+			start := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, base.Location())
+			if !start.Before(base) {
+				starts = append(starts, start)
+			}
+		}
+	}
+	if len(starts) > maxTemplateTasks {
+		return nil, ErrTooManyTasks
+	}
+	return starts, nil
+}
+
+// startSeries is a series of starts, given by their indexes, that follow rule.
+type startSeries struct {
+	rule   string
+	starts []int
+}
+
+// templateSeries groups the starts of the template by time of day into
+// series that calendar feeds export with an RRULE. Starts of a time of day
+// that occurs once are left out.
+func templateSeries(template task_manager.TaskTemplate, starts []time.Time) []startSeries {
+	rec, err := parseRecurrence(template.Recurrence)
+	if err != nil {
+		return nil
+	}
+	var clocks []string
+	groups := make(map[string][]int)
+	for i, start := range starts {
+		clock := start.Format(time.TimeOnly)
+		if _, ok := groups[clock]; !ok {
+			clocks = append(clocks, clock)
+		}
+		groups[clock] = append(groups[clock], i)
+	}
+
+	var series []startSeries
+	for _, clock := range clocks {
+		times := make([]time.Time, len(groups[clock]))
+		for i, index := range groups[clock] {
+			times[i] = starts[index]
+		}
+		if rule, ok := rec.seriesRule(times); ok {
+			series = append(series, startSeries{rule: rule, starts: groups[clock]})
+		}
+	}
+	return series
+}
+
+// renderTemplate substitutes the placeholders of text for the n-th task starting at start.
+func renderTemplate(text string, start time.Time, n int) string {
+	return strings.NewReplacer(
+		"{date}", start.Format(time.DateOnly),
+		"{time}", start.Format("15:04"),
+		"{weekday}", start.Weekday().String(),
+		"{n}", strconv.Itoa(n),
+	).Replace(text)
+}
+
+func validateTemplate(input task_manager.TaskTemplateInput) (task_manager.TaskTemplateInput, error) {
+	if input.Priority == "" {
+		input.Priority = task_manager.PriorityNormal
+	}
+	if !input.Priority.Valid() {
+		return input, ErrInvalidPriority
+	}
+	for i, tag := range input.Tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return input, err
+		}
+		input.Tags[i] = name
+	}
+	for _, value := range input.Times {
+		if _, _, err := parseTimeOfDay(value); err != nil {
+			return input, err
+		}
+	}
+	if _, err := parseRecurrence(input.Recurrence); err != nil {
+		return input, err
+	}
+	return input, nil
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"net/url"
+	"task_manager"
+	"task_manager/pkg/repository"
+	"task_manager/pkg/tracing"
+	"task_manager/pkg/webhook"
+	"time"
+)
+
+const (
+	webhookSecretBytes       = 32
+	webhookDeliveryInterval  = 5 * time.Second
+	webhookDeliveryBatchSize = 100
+	// webhookDeliveryLease hides claimed deliveries from other workers
+	// while they are sent.
+	webhookDeliveryLease = time.Minute
+	// webhookBatchTimeout bounds sending a claimed batch well within the
+	// lease, so every attempt is recorded before the deliveries can be
+	// claimed again.
+	webhookBatchTimeout = 40 * time.Second
+	// webhookSenders is how many webhooks of a batch are sent to at once.
+	webhookSenders         = 16
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+	maxWebhookErrorLength  = 1000
+	defaultWebhookAttempts = 8
+	minWebhookRetryDelay   = 10 * time.Second
+	maxWebhookRetryDelay   = 6 * time.Hour
+)
+
+var ErrInvalidWebhook = errors.New("webhook needs an http(s) url and known event types")
+
+// WebhookService manages the webhooks of users and delivers their events.
+// It is an outbox sink: events are queued as deliveries of every matching
+// webhook, which are sent and retried independently of each other.
+type WebhookService struct {
+	repo        repository.Webhook
+	client      *webhook.Client
+	maxAttempts int
+	logger      *slog.Logger
+}
+
+func NewWebhookService(repo repository.Webhook, client *webhook.Client, maxAttempts int, logger *slog.Logger) *WebhookService {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookAttempts
+	}
+	return &WebhookService{repo: repo, client: client, maxAttempts: maxAttempts, logger: logger}
+}
+
+func (s *WebhookService) GetAll(ctx context.Context, telegramId int) (webhooks []task_manager.Webhook, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	webhooks, err = s.repo.GetAll(ctx, telegramId)
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, err
+}
+
+func (s *WebhookService) GetById(ctx context.Context, webhookId int) (hook task_manager.Webhook, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("webhook.id", webhookId))
+	hook, err = s.repo.GetById(ctx, webhookId)
+	hook.Secret = ""
+	return hook, err
+}
+
+// Create returns the webhook with its secret, generated unless given.
+func (s *WebhookService) Create(ctx context.Context, telegramId int, input task_manager.WebhookInput) (hook task_manager.Webhook, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("telegram.id", telegramId))
+	if !validWebhook(input) {
+		return hook, ErrInvalidWebhook
+	}
+	if input.Secret == "" {
+		secret := make([]byte, webhookSecretBytes)
+		if _, err = rand.Read(secret); err != nil {
+			return hook, err
+		}
+		input.Secret = hex.EncodeToString(secret)
+	}
+	if hook, err = s.repo.Create(ctx, telegramId, input); err != nil {
+		return hook, err
+	}
+	s.logger.InfoContext(ctx, "webhook created", "webhook_id", hook.Id, "telegram_id", telegramId)
+	return hook, nil
+}
+
+func (s *WebhookService) Update(ctx context.Context, webhookId int, input task_manager.WebhookInput) (hook task_manager.Webhook, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("webhook.id", webhookId))
+	if !validWebhook(input) {
+		return hook, ErrInvalidWebhook
+	}
+	hook, err = s.repo.Update(ctx, webhookId, input)
+	hook.Secret = ""
+	return hook, err
+}
+
+func (s *WebhookService) Delete(ctx context.Context, webhookId int) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("webhook.id", webhookId))
+	return s.repo.Delete(ctx, webhookId)
+}
+
+// GetDeliveries returns the latest deliveries of the webhook, newest first.
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookId int, limit int) (deliveries []task_manager.WebhookDelivery, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.GetDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("webhook.id", webhookId))
+	if _, err = s.repo.GetById(ctx, webhookId); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+	return s.repo.GetDeliveries(ctx, webhookId, min(limit, maxDeliveriesLimit))
+}
+
+// SendTest sends a webhook.test event right away and returns its delivery.
+// Failed test deliveries are not retried.
+func (s *WebhookService) SendTest(ctx context.Context, webhookId int) (delivery task_manager.WebhookDelivery, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.SendTest")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.Int("webhook.id", webhookId))
+	hook, err := s.repo.GetById(ctx, webhookId)
+	if err != nil {
+		return delivery, err
+	}
+	body, err := json.Marshal(task_manager.Event{
+		Type:       task_manager.EventWebhookTest,
+		TelegramId: hook.TelegramId,
+		Payload:    json.RawMessage(`{}`),
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return delivery, err
+	}
+	delivery, err = s.repo.CreateDelivery(ctx, webhookId, task_manager.EventWebhookTest, body, webhookDeliveryLease)
+	if err != nil {
+		return delivery, err
+	}
+
+	pending := task_manager.PendingDelivery{WebhookDelivery: delivery, Url: hook.Url, Secret: hook.Secret}
+	if err = s.send(ctx, ctx, pending, true); err != nil {
+		return delivery, err
+	}
+	deliveries, err := s.repo.GetDeliveries(ctx, webhookId, 1)
+	if err != nil || len(deliveries) == 0 {
+		return delivery, err
+	}
+	return deliveries[0], nil
+}
+
+// Deliver queues the event for the webhooks subscribed to it.
+func (s *WebhookService) Deliver(ctx context.Context, event task_manager.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.Enqueue(ctx, event, body)
+	return err
+}
+
+// RunDelivery periodically sends due deliveries.
+func (s *WebhookService) RunDelivery(ctx context.Context) error {
+	ticker := time.NewTicker(webhookDeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick sends due deliveries until none are left.
+func (s *WebhookService) tick(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := s.sendBatch(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to send webhooks", "error", err)
+			return
+		}
+		if n < webhookDeliveryBatchSize {
+			return
+		}
+	}
+}
+
+// sendBatch claims due deliveries and sends them. Deliveries of a webhook
+// are sent in order and different webhooks concurrently, so a slow
+// endpoint only holds back its own deliveries. Those not sent before
+// webhookBatchTimeout stay claimed and are sent again after the lease.
+func (s *WebhookService) sendBatch(ctx context.Context) (n int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.sendBatch")
+	defer func() { tracing.End(span, err) }()
+
+	deliveries, err := s.repo.ClaimDeliveries(ctx, webhookDeliveryBatchSize, webhookDeliveryLease)
+	if err != nil {
+		return 0, err
+	}
+	var webhookIds []int
+	byWebhook := make(map[int][]task_manager.PendingDelivery)
+	for _, delivery := range deliveries {
+		if _, ok := byWebhook[delivery.WebhookId]; !ok {
+			webhookIds = append(webhookIds, delivery.WebhookId)
+		}
+		byWebhook[delivery.WebhookId] = append(byWebhook[delivery.WebhookId], delivery)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, webhookBatchTimeout)
+	defer cancel()
+	var group errgroup.Group
+	group.SetLimit(webhookSenders)
+	for _, webhookId := range webhookIds {
+		pending := byWebhook[webhookId]
+		group.Go(func() error {
+			for _, delivery := range pending {
+				if sendCtx.Err() != nil {
+					return nil
+				}
+				if err := s.send(ctx, sendCtx, delivery, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err = group.Wait(); err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// send makes an attempt of the delivery with requestCtx and records its
+// outcome with ctx, giving up after the last attempt or, with once, after
+// the first one.
+func (s *WebhookService) send(ctx, requestCtx context.Context, delivery task_manager.PendingDelivery, once bool) error {
+	code, err := s.client.Send(requestCtx, webhook.Request{
+		Url:        delivery.Url,
+		Secret:     delivery.Secret,
+		DeliveryId: delivery.Id,
+		Event:      string(delivery.EventType),
+		Body:       delivery.Payload,
+	})
+	if err == nil {
+		return s.repo.MarkDelivered(ctx, delivery.Id, code)
+	}
+
+	var responseCode *int
+	if code != 0 {
+		responseCode = &code
+	}
+	reason := err.Error()
+	if len(reason) > maxWebhookErrorLength {
+		reason = reason[:maxWebhookErrorLength]
+	}
+	if once || delivery.Attempts >= s.maxAttempts {
+		s.logger.WarnContext(ctx, "webhook delivery failed", "delivery_id", delivery.Id, "webhook_id", delivery.WebhookId,
+			"attempts", delivery.Attempts, "error", reason)
+		return s.repo.MarkDead(ctx, delivery.Id, responseCode, reason)
+	}
+	delay := backoff(delivery.Attempts, minWebhookRetryDelay, maxWebhookRetryDelay)
+	s.logger.DebugContext(ctx, "webhook delivery will be retried", "delivery_id", delivery.Id,
+		"attempts", delivery.Attempts, "retry_in", delay, "error", reason)
+	return s.repo.MarkFailed(ctx, delivery.Id, responseCode, reason, delay)
+}
+
+func validWebhook(input task_manager.WebhookInput) bool {
+	u, err := url.Parse(input.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if len(input.EventTypes) == 0 {
+		return false
+	}
+	for _, eventType := range input.EventTypes {
+		if !eventType.Valid() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+)
+
+func TestSendSignsRequest(t *testing.T) {
+	const secret = "secret"
+	body := []byte(`{"type":"task.created"}`)
+
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		if string(got) != string(body) {
+			t.Errorf("body = %s, want %s", got, body)
+		}
+		if !Verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), got) {
+			t.Errorf("signature %q does not verify", r.Header.Get(SignatureHeader))
+		}
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	client := NewClient(NewHTTPClient(true))
+	code, err := client.Send(context.Background(), Request{
+		Url:        receiver.URL,
+		Secret:     secret,
+		DeliveryId: 42,
+		Event:      "task.created",
+		Body:       body,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if code != http.StatusNoContent {
+		t.Errorf("code = %d, want %d", code, http.StatusNoContent)
+	}
+
+	r := <-received
+	if r.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", r.Method)
+	}
+	if got := r.Header.Get(EventHeader); got != "task.created" {
+		t.Errorf("%s = %q, want task.created", EventHeader, got)
+	}
+	if got := r.Header.Get(DeliveryHeader); got != strconv.Itoa(42) {
+		t.Errorf("%s = %q, want 42", DeliveryHeader, got)
+	}
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestSendStatusError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	code, err := NewClient(NewHTTPClient(true)).Send(context.Background(), Request{Url: receiver.URL, Body: []byte(`{}`)})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want StatusError with 503", err)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("code = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	code, err := NewClient(nil).Send(context.Background(), Request{Url: receiver.URL, Body: []byte(`{}`)})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("err = %v, want ErrForbiddenAddress", err)
+	}
+	if code != 0 || called {
+		t.Errorf("request reached the receiver")
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
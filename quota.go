@@ -0,0 +1,16 @@
+package task_manager
+
+// QuotaLimits bounds what a telegram user may create. Zero means unlimited.
+type QuotaLimits struct {
+	MaxActiveTasks int `json:"max_active_tasks"`
+	MaxTasksPerDay int `json:"max_tasks_per_day"`
+	MaxTextLength  int `json:"max_text_length"`
+}
+
+type QuotaUsage struct {
+	TelegramId  int         `json:"telegram_id"`
+	Tier        string      `json:"tier"`
+	Limits      QuotaLimits `json:"limits"`
+	ActiveTasks int         `json:"active_tasks"`
+	TasksToday  int         `json:"tasks_today"`
+}
@@ -0,0 +1,36 @@
+package task_manager
+
+import (
+	"time"
+)
+
+// TaskReminder fires either at an absolute time or at an offset relative
+// to the task time, which is its due time if set and start time otherwise.
+type TaskReminder struct {
+	Id            int        `json:"id" db:"id"`
+	TaskId        int        `json:"task_id" db:"task_id"`
+	RemindAt      *time.Time `json:"remind_at" db:"remind_at"`
+	OffsetSeconds *int       `json:"offset_seconds" db:"offset_seconds"`
+	FireAt        time.Time  `json:"fire_at" db:"fire_at"`
+	FiredAt       *time.Time `json:"fired_at" db:"fired_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// FiredReminder is a reminder claimed by the scheduler with the task it
+// belongs to.
+type FiredReminder struct {
+	TaskReminder
+	TelegramId int    `json:"telegram_id" db:"telegram_id"`
+	Text       string `json:"text" db:"text"`
+}
+
+type TaskReminderInput struct {
+	RemindAt      *time.Time
+	RemindAtStr   *string `form:"remind_at" json:"remind_at"`
+	OffsetSeconds *int    `form:"offset_seconds" json:"offset_seconds"`
+}
+
+// Valid reports whether exactly one of RemindAt and OffsetSeconds is set.
+func (i TaskReminderInput) Valid() bool {
+	return (i.RemindAt == nil) != (i.OffsetSeconds == nil)
+}
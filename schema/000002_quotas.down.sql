@@ -0,0 +1,3 @@
+DROP INDEX tasks_telegram_id_created_at_idx;
+
+DROP TABLE user_tiers;
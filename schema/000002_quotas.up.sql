@@ -0,0 +1,8 @@
+CREATE TABLE user_tiers
+(
+    telegram_id varchar(20) not null unique,
+    tier        text        not null,
+    created_at  timestamp   not null default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tasks_telegram_id_created_at_idx ON tasks (telegram_id, created_at);
@@ -0,0 +1,15 @@
+CREATE TABLE idempotency_keys
+(
+    id           serial    not null unique,
+    principal    text      not null,
+    key          text      not null,
+    fingerprint  text      not null,
+    status_code  integer,
+    content_type text,
+    response     bytea,
+    created_at   timestamp not null default CURRENT_TIMESTAMP,
+    expires_at   timestamp not null,
+    UNIQUE (principal, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
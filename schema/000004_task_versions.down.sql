@@ -0,0 +1,5 @@
+DROP TRIGGER increment_task_version ON tasks;
+
+drop function increment_version_task();
+
+ALTER TABLE tasks DROP COLUMN version;
@@ -0,0 +1,18 @@
+ALTER TABLE tasks ADD COLUMN version integer not null default 1;
+
+CREATE FUNCTION increment_version_task()
+    RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version
+= OLD.version + 1;
+RETURN NEW;
+END;
+$$
+language 'plpgsql';
+
+CREATE TRIGGER increment_task_version
+    BEFORE UPDATE
+    ON
+        tasks
+    FOR EACH ROW
+    EXECUTE PROCEDURE increment_version_task();
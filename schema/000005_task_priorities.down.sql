@@ -0,0 +1,5 @@
+DROP INDEX tasks_telegram_id_due_at_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN priority,
+    DROP COLUMN due_at;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+    ADD COLUMN priority text not null default 'normal' check ( priority in ('low', 'normal', 'high', 'urgent') ),
+    ADD COLUMN due_at   timestamp;
+
+CREATE INDEX tasks_telegram_id_due_at_idx ON tasks (telegram_id, due_at);
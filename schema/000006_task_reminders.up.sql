@@ -0,0 +1,14 @@
+CREATE TABLE task_reminders
+(
+    id             serial                                      not null unique,
+    task_id        int references tasks (id) on delete cascade not null,
+    remind_at      timestamp,
+    offset_seconds integer,
+    fired_at       timestamp,
+    created_at     timestamp                                   not null default CURRENT_TIMESTAMP,
+    check ( (remind_at is null) <> (offset_seconds is null) )
+);
+
+CREATE INDEX task_reminders_task_id_idx ON task_reminders (task_id);
+
+CREATE INDEX task_reminders_unfired_idx ON task_reminders (task_id) WHERE fired_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE tasks_tags;
+
+DROP TABLE tags;
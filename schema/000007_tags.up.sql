@@ -0,0 +1,17 @@
+CREATE TABLE tags
+(
+    id          serial      not null unique,
+    telegram_id varchar(20) not null,
+    name        text        not null,
+    created_at  timestamp   not null default CURRENT_TIMESTAMP,
+    UNIQUE (telegram_id, name)
+);
+
+CREATE TABLE tasks_tags
+(
+    task_id int references tasks (id) on delete cascade not null,
+    tag_id  int references tags (id) on delete cascade  not null,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX tasks_tags_tag_id_idx ON tasks_tags (tag_id);
@@ -0,0 +1,8 @@
+DROP INDEX tasks_list_id_position_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN list_id,
+    DROP COLUMN position,
+    DROP COLUMN archived_at;
+
+DROP TABLE lists;
@@ -0,0 +1,18 @@
+CREATE TABLE lists
+(
+    id          serial      not null unique,
+    telegram_id varchar(20) not null,
+    title       text        not null,
+    position    integer     not null default 0,
+    created_at  timestamp   not null default CURRENT_TIMESTAMP,
+    archived_at timestamp
+);
+
+CREATE INDEX lists_telegram_id_idx ON lists (telegram_id);
+
+ALTER TABLE tasks
+    ADD COLUMN list_id     int references lists (id) on delete set null,
+    ADD COLUMN position    integer not null default 0,
+    ADD COLUMN archived_at timestamp;
+
+CREATE INDEX tasks_list_id_position_idx ON tasks (list_id, position);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN checklist_auto_complete;
+
+DROP TABLE checklist_items;
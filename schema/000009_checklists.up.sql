@@ -0,0 +1,14 @@
+CREATE TABLE checklist_items
+(
+    id         serial                                      not null unique,
+    task_id    int references tasks (id) on delete cascade not null,
+    text       text                                        not null,
+    done       boolean                                     not null default false,
+    position   integer                                     not null default 0,
+    created_at timestamp                                   not null default CURRENT_TIMESTAMP,
+    done_at    timestamp
+);
+
+CREATE INDEX checklist_items_task_id_position_idx ON checklist_items (task_id, position);
+
+ALTER TABLE tasks ADD COLUMN checklist_auto_complete boolean not null default false;
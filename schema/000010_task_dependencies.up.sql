@@ -0,0 +1,10 @@
+CREATE TABLE task_dependencies
+(
+    task_id       int references tasks (id) on delete cascade not null,
+    blocked_by_id int references tasks (id) on delete cascade not null,
+    created_at    timestamp                                   not null default CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, blocked_by_id),
+    check ( task_id <> blocked_by_id )
+);
+
+CREATE INDEX task_dependencies_blocked_by_id_idx ON task_dependencies (blocked_by_id);
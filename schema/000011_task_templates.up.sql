@@ -0,0 +1,15 @@
+CREATE TABLE task_templates
+(
+    id               serial      not null unique,
+    telegram_id      varchar(20) not null,
+    name             text        not null,
+    text             text        not null,
+    priority         text        not null default 'normal' check ( priority in ('low', 'normal', 'high', 'urgent') ),
+    tags             text[]      not null default '{}',
+    times            text[]      not null default '{}',
+    reminder_offsets integer[]   not null default '{}',
+    recurrence       text        not null default '',
+    created_at       timestamp   not null default CURRENT_TIMESTAMP,
+    updated_at       timestamp   not null default CURRENT_TIMESTAMP,
+    UNIQUE (telegram_id, name)
+);
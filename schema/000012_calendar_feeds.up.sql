@@ -0,0 +1,6 @@
+CREATE TABLE calendar_feeds
+(
+    telegram_id varchar(20) not null unique,
+    token_hash  text        not null,
+    created_at  timestamp   not null default CURRENT_TIMESTAMP
+);
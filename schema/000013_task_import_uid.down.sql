@@ -0,0 +1,3 @@
+DROP INDEX tasks_telegram_id_import_uid_idx;
+
+ALTER TABLE tasks DROP COLUMN import_uid;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN import_uid text;
+
+CREATE UNIQUE INDEX tasks_telegram_id_import_uid_idx ON tasks (telegram_id, import_uid);
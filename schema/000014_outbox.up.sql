@@ -0,0 +1,15 @@
+CREATE TABLE outbox
+(
+    id              bigserial   not null unique,
+    event_type      text        not null,
+    telegram_id     varchar(20) not null,
+    payload         jsonb       not null,
+    created_at      timestamp   not null default CURRENT_TIMESTAMP,
+    attempts        integer     not null default 0,
+    next_attempt_at timestamp   not null default CURRENT_TIMESTAMP,
+    last_error      text,
+    delivered_at    timestamp,
+    dead_at         timestamp
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at) WHERE delivered_at IS NULL AND dead_at IS NULL;
@@ -0,0 +1,33 @@
+CREATE TABLE webhooks
+(
+    id          serial      not null unique,
+    telegram_id varchar(20) not null,
+    url         text        not null,
+    secret      text        not null,
+    event_types text[]      not null,
+    created_at  timestamp   not null default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhooks_telegram_id_idx ON webhooks (telegram_id);
+
+CREATE TABLE webhook_deliveries
+(
+    id              bigserial                                      not null unique,
+    webhook_id      int references webhooks (id) on delete cascade not null,
+    event_id        bigint,
+    event_type      text                                           not null,
+    payload         jsonb                                          not null,
+    status          text                                           not null default 'pending'
+        check ( status in ('pending', 'delivered', 'dead') ),
+    attempts        integer                                        not null default 0,
+    response_code   integer,
+    last_error      text,
+    next_attempt_at timestamp                                      not null default CURRENT_TIMESTAMP,
+    created_at      timestamp                                      not null default CURRENT_TIMESTAMP,
+    delivered_at    timestamp,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
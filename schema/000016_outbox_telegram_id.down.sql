@@ -0,0 +1 @@
+DROP INDEX outbox_telegram_id_idx;
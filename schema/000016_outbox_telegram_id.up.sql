@@ -0,0 +1 @@
+CREATE INDEX outbox_telegram_id_idx ON outbox (telegram_id, id);
@@ -0,0 +1,2 @@
+DROP TRIGGER rearm_task_reminders ON tasks;
+DROP FUNCTION rearm_task_reminders();
@@ -0,0 +1,21 @@
+CREATE FUNCTION rearm_task_reminders()
+    RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE task_reminders
+    SET fired_at = NULL
+    WHERE task_id = NEW.id
+      AND offset_seconds IS NOT NULL
+      AND fired_at IS NOT NULL
+      AND COALESCE(NEW.due_at, NEW.start_time_at) + offset_seconds * interval '1 second' > CURRENT_TIMESTAMP;
+RETURN NEW;
+END;
+$$
+language 'plpgsql';
+
+CREATE TRIGGER rearm_task_reminders
+    AFTER UPDATE OF start_time_at, due_at
+    ON
+        tasks
+    FOR EACH ROW
+    WHEN (NEW.start_time_at IS DISTINCT FROM OLD.start_time_at OR NEW.due_at IS DISTINCT FROM OLD.due_at)
+    EXECUTE PROCEDURE rearm_task_reminders();
@@ -0,0 +1,6 @@
+DROP INDEX outbox_finished_idx;
+DROP INDEX outbox_telegram_id_idx;
+DROP INDEX outbox_stream_idx;
+CREATE INDEX outbox_telegram_id_idx ON outbox (telegram_id, id);
+
+ALTER TABLE outbox DROP COLUMN txid;
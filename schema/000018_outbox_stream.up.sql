@@ -0,0 +1,6 @@
+ALTER TABLE outbox ADD COLUMN txid bigint NOT NULL DEFAULT txid_current();
+
+DROP INDEX outbox_telegram_id_idx;
+CREATE INDEX outbox_stream_idx ON outbox (txid, id);
+CREATE INDEX outbox_telegram_id_idx ON outbox (telegram_id, txid, id);
+CREATE INDEX outbox_finished_idx ON outbox (created_at) WHERE delivered_at IS NOT NULL OR dead_at IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+    DROP COLUMN recurrence_id,
+    DROP COLUMN series_id;
+
+DROP TABLE task_series;
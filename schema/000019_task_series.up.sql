@@ -0,0 +1,16 @@
+CREATE TABLE task_series
+(
+    id            serial      not null unique,
+    telegram_id   varchar(20) not null,
+    recurrence    text        not null,
+    start_time_at timestamp   not null,
+    created_at    timestamp   not null default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX task_series_telegram_id_idx ON task_series (telegram_id);
+
+ALTER TABLE tasks
+    ADD COLUMN series_id     integer references task_series (id) on delete set null,
+    ADD COLUMN recurrence_id timestamp;
+
+CREATE INDEX tasks_series_id_idx ON tasks (series_id) WHERE series_id IS NOT NULL;
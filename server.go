@@ -2,28 +2,176 @@ package task_manager
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
 
+const defaultShutdownTimeout = 15 * time.Second
+
+// Worker is a background process run next to the HTTP server. It must
+// return once ctx is cancelled.
+type Worker func(ctx context.Context) error
+
+// ErrShutdownTimeout is returned by Server.Run when workers did not stop
+// within the shutdown timeout.
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+type shutdownKey struct{}
+
+// ShutdownContext returns a context that is done when the shutdown timeout
+// of the server runs out, so a worker can bound its cleanup after its ctx
+// is cancelled. Outside of workers it is never done.
+func ShutdownContext(ctx context.Context) context.Context {
+	if expired, ok := ctx.Value(shutdownKey{}).(context.Context); ok {
+		return expired
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// Closer releases a resource after the server and all workers stopped.
+type Closer func(ctx context.Context) error
+
+type ServerConfig struct {
+	Port string
+	// ShutdownTimeout bounds the whole shutdown: draining of in-flight
+	// requests, stopping of workers and the closers run afterwards.
+	ShutdownTimeout time.Duration
+}
+
+type namedWorker struct {
+	name string
+	run  Worker
+}
+
+type namedCloser struct {
+	name  string
+	close Closer
+}
+
+// Server runs the HTTP server and background workers until the context
+// passed to Run is cancelled or any of them fails.
 type Server struct {
-	httpServer *http.Server
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+	workers         []namedWorker
+	closers         []namedCloser
+	logger          *slog.Logger
 }
 
-func (s *Server) Run(port string, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 20, // 1 MB
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+func NewServer(cfg ServerConfig, handler http.Handler, logger *slog.Logger) *Server {
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
 	}
-	if s.httpServer.Addr == ":" {
-		s.httpServer.Addr = ""
+	return &Server{
+		httpServer: &http.Server{
+			Addr:           ":" + cfg.Port,
+			Handler:        handler,
+			MaxHeaderBytes: 1 << 20, // 1 MB
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   10 * time.Second,
+		},
+		shutdownTimeout: shutdownTimeout,
+		logger:          logger,
 	}
-	return s.httpServer.ListenAndServe()
 }
 
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+func (s *Server) AddWorker(name string, worker Worker) {
+	s.workers = append(s.workers, namedWorker{name: name, run: worker})
+}
+
+// OnShutdown registers a function to call when the HTTP server starts
+// draining, such as one ending long-lived streams that would otherwise
+// hold it until the shutdown timeout.
+func (s *Server) OnShutdown(f func()) {
+	s.httpServer.RegisterOnShutdown(f)
+}
+
+// AddCloser registers a resource to release on shutdown. Closers run in
+// the order they were added, so the database should be added last.
+func (s *Server) AddCloser(name string, closer Closer) {
+	s.closers = append(s.closers, namedCloser{name: name, close: closer})
+}
+
+// Run blocks until ctx is cancelled or a component fails. Listen errors are
+// returned immediately. On shutdown the HTTP server drains in-flight
+// requests, then workers are stopped and closers run, all before one
+// deadline set when shutdown starts.
+func (s *Server) Run(ctx context.Context) error {
+	addr := s.httpServer.Addr
+	if addr == ":" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		closeCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		s.close(closeCtx)
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	s.logger.Info("http server listening", "addr", listener.Addr().String())
+
+	// expired is done at the shutdown deadline.
+	expired, expire := context.WithCancel(context.Background())
+	defer expire()
+	group, groupCtx := errgroup.WithContext(ctx)
+	workersCtx, stopWorkers := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), shutdownKey{}, expired))
+	defer stopWorkers()
+
+	group.Go(func() error {
+		if err := s.httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server: %w", err)
+		}
+		return nil
+	})
+	for _, worker := range s.workers {
+		worker := worker
+		group.Go(func() error {
+			s.logger.Info("worker started", "worker", worker.name)
+			err := worker.run(workersCtx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("worker %s: %w", worker.name, err)
+			}
+			s.logger.Info("worker stopped", "worker", worker.name)
+			return nil
+		})
+	}
+	group.Go(func() error {
+		<-groupCtx.Done()
+		s.logger.Info("draining http server", "timeout", s.shutdownTimeout)
+		time.AfterFunc(s.shutdownTimeout, expire)
+
+		err := s.httpServer.Shutdown(expired)
+		stopWorkers()
+		if err != nil {
+			return fmt.Errorf("http server shutdown: %w", err)
+		}
+		return nil
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- group.Wait()
+	}()
+	select {
+	case err = <-stopped:
+	case <-expired.Done():
+		err = ErrShutdownTimeout
+		s.logger.Error("workers did not stop within the shutdown timeout")
+	}
+	s.close(expired)
+	return err
+}
+
+func (s *Server) close(ctx context.Context) {
+	for _, closer := range s.closers {
+		if err := closer.close(ctx); err != nil {
+			s.logger.Error("failed to close resource", "resource", closer.name, "error", err)
+		}
+	}
 }
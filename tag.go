@@ -0,0 +1,30 @@
+package task_manager
+
+import (
+	"time"
+)
+
+// Tag names are stored lower case without the leading '#'.
+type Tag struct {
+	Id         int       `json:"id" db:"id"`
+	TelegramId int       `json:"telegram_id" db:"telegram_id"`
+	Name       string    `json:"name" db:"name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	TaskCount  int       `json:"task_count" db:"task_count"`
+}
+
+type TagInput struct {
+	Name string `form:"name" json:"name" binding:"required"`
+}
+
+type MergeTagsInput struct {
+	IntoId int `form:"into_id" json:"into_id" binding:"required"`
+}
+
+// TagMode combines several tags of a TaskFilter.
+type TagMode string
+
+const (
+	TagModeAnd TagMode = "and"
+	TagModeOr  TagMode = "or"
+)
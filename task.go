@@ -1,6 +1,7 @@
 package task_manager
 
 import (
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -13,6 +14,26 @@ type Task struct {
 	StartTimeAt time.Time  `json:"start_time_at" db:"start_time_at"`
 	StatusEnd   StatusEnd  `json:"status_end" db:"status_end"`
 	EndTask     *time.Time `json:"end_task_at" db:"end_task_at"`
+	Version     int        `json:"version" db:"version"`
+	Priority    Priority   `json:"priority" db:"priority"`
+	DueAt       *time.Time `json:"due_at" db:"due_at"`
+	// Overdue is computed: the task is unfinished and its due time passed.
+	Overdue    bool           `json:"overdue" db:"overdue"`
+	Tags       pq.StringArray `json:"tags" db:"tags" swaggertype:"array,string"`
+	ListId     *int           `json:"list_id" db:"list_id"`
+	Position   int            `json:"position" db:"position"`
+	ArchivedAt *time.Time     `json:"archived_at" db:"archived_at"`
+	// ChecklistAutoComplete completes the task once all checklist items are done.
+	ChecklistAutoComplete bool `json:"checklist_auto_complete" db:"checklist_auto_complete"`
+	// Blocked is computed: some of the BlockedBy tasks are unfinished.
+	Blocked   bool          `json:"blocked" db:"blocked"`
+	BlockedBy pq.Int64Array `json:"blocked_by" db:"blocked_by" swaggertype:"array,integer"`
+	// ImportUid is the UID of the imported calendar component the task was created from.
+	ImportUid *string `json:"import_uid" db:"import_uid"`
+	// SeriesId is the series of template tasks the task belongs to and
+	// RecurrenceId the start time it was created with in the series.
+	SeriesId     *int       `json:"series_id" db:"series_id"`
+	RecurrenceId *time.Time `json:"recurrence_id" db:"recurrence_id"`
 }
 
 type StatusEnd string
@@ -22,11 +43,93 @@ const (
 	End   StatusEnd = "END"
 )
 
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// TaskSort orders task lists. SortDefault puts overdue tasks first, then
+// sorts by priority and due time.
+type TaskSort string
+
+const (
+	SortDefault   TaskSort = ""
+	SortPriority  TaskSort = "priority"
+	SortDueAt     TaskSort = "due_at"
+	SortStartTime TaskSort = "start_time"
+	SortCreatedAt TaskSort = "created_at"
+)
+
+func (s TaskSort) Valid() bool {
+	switch s {
+	case SortDefault, SortPriority, SortDueAt, SortStartTime, SortCreatedAt:
+		return true
+	}
+	return false
+}
+
+type TaskFilter struct {
+	Sort TaskSort
+	// Tags keeps tasks having all (TagModeAnd) or any (TagModeOr) of the tags.
+	Tags    []string
+	TagMode TagMode
+	// ListId keeps tasks of one list, ordered by position unless Sort is given.
+	ListId int
+	// Archived includes tasks of archived lists.
+	Archived bool
+	// Limit caps the number of tasks after skipping the first Offset of
+	// them; zero returns all tasks.
+	Limit  int
+	Offset int
+}
+
 type CreateTaskInput struct {
 	Text         string `json:"text"`
 	StartTime    time.Time
-	StartTimeStr string `json:"start_time"`
-	TelegramId   string `json:"telegram_id"`
+	StartTimeStr string     `json:"start_time"`
+	TelegramId   string     `json:"telegram_id"`
+	Priority     Priority   `json:"priority"`
+	DueAt        *time.Time `json:"due_at"`
+	ListId       *int       `json:"list_id"`
+	// Tags are added to the hashtags of Text.
+	Tags []string `json:"tags"`
+	// ChecklistAutoComplete completes the task once all checklist items are done.
+	ChecklistAutoComplete bool `json:"checklist_auto_complete"`
+	// ImportUid deduplicates imported tasks of a user.
+	ImportUid *string `json:"-"`
+	// SeriesId adds the task to a series with its start time as the
+	// recurrence id.
+	SeriesId *int `json:"-"`
+}
+
+type UpdateTaskInput struct {
+	Text         *string `form:"text" json:"text"`
+	StartTime    *time.Time
+	StartTimeStr *string   `form:"start_time" json:"start_time"`
+	Priority     *Priority `form:"priority" json:"priority"`
+	DueAt        *time.Time
+	DueAtStr     *string `form:"due_at" json:"due_at"`
+	// ClearDueAt removes the due time; it cannot be combined with DueAt.
+	ClearDueAt bool `form:"clear_due_at" json:"clear_due_at"`
+
+	ChecklistAutoComplete *bool `form:"checklist_auto_complete" json:"checklist_auto_complete"`
+}
+
+func (i UpdateTaskInput) Empty() bool {
+	return i.Text == nil && i.StartTime == nil && i.Priority == nil && i.DueAt == nil && !i.ClearDueAt &&
+		i.ChecklistAutoComplete == nil
 }
 
 type CreateTaskInputModeration struct {
@@ -0,0 +1,42 @@
+package task_manager
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// TaskTemplate describes tasks created repeatedly. Text may contain the
+// placeholders {date}, {time}, {weekday} and {n}, rendered for every
+// created task.
+type TaskTemplate struct {
+	Id         int            `json:"id" db:"id"`
+	TelegramId int            `json:"telegram_id" db:"telegram_id"`
+	Name       string         `json:"name" db:"name"`
+	Text       string         `json:"text" db:"text"`
+	Priority   Priority       `json:"priority" db:"priority"`
+	Tags       pq.StringArray `json:"tags" db:"tags" swaggertype:"array,string"`
+	// Times are "15:04" times of day of the tasks created for every occurrence.
+	Times pq.StringArray `json:"times" db:"times" swaggertype:"array,string"`
+	// ReminderOffsets are offsets in seconds of the reminders of created tasks.
+	ReminderOffsets pq.Int64Array `json:"reminder_offsets" db:"reminder_offsets" swaggertype:"array,integer"`
+	// Recurrence is an RRULE with FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL,
+	// BYDAY and COUNT or UNTIL. Empty means a single occurrence.
+	Recurrence string    `json:"recurrence" db:"recurrence"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type TaskTemplateInput struct {
+	Name            string   `json:"name" binding:"required"`
+	Text            string   `json:"text" binding:"required"`
+	Priority        Priority `json:"priority"`
+	Tags            []string `json:"tags"`
+	Times           []string `json:"times"`
+	ReminderOffsets []int64  `json:"reminder_offsets"`
+	Recurrence      string   `json:"recurrence"`
+}
+
+type InstantiateTemplateInput struct {
+	BaseTime    time.Time
+	BaseTimeStr string `form:"base_time" json:"base_time" binding:"required"`
+}
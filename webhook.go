@@ -0,0 +1,57 @@
+package task_manager
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Webhook posts the events of EventTypes of the user to Url, signed with
+// Secret. The secret is only returned when the webhook is created.
+type Webhook struct {
+	Id         int            `json:"id" db:"id"`
+	TelegramId int            `json:"telegram_id" db:"telegram_id"`
+	Url        string         `json:"url" db:"url"`
+	Secret     string         `json:"secret,omitempty" db:"secret"`
+	EventTypes pq.StringArray `json:"event_types" db:"event_types" swaggertype:"array,string"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+type WebhookInput struct {
+	Url string `json:"url" binding:"required"`
+	// Secret is generated when empty on create and kept when empty on update.
+	Secret     string      `json:"secret"`
+	EventTypes []EventType `json:"event_types" binding:"required,min=1"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is an entry of the delivery log of a webhook. EventId is
+// empty for test events.
+type WebhookDelivery struct {
+	Id            int64           `json:"id" db:"id"`
+	WebhookId     int             `json:"webhook_id" db:"webhook_id"`
+	EventId       *int64          `json:"event_id" db:"event_id"`
+	EventType     EventType       `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Status        DeliveryStatus  `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	ResponseCode  *int            `json:"response_code" db:"response_code"`
+	LastError     *string         `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at" db:"delivered_at"`
+}
+
+// PendingDelivery is a claimed delivery with the target of its webhook.
+type PendingDelivery struct {
+	WebhookDelivery
+	Url    string `db:"url"`
+	Secret string `db:"secret"`
+}